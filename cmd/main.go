@@ -1,29 +1,142 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/cache"
 	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
 	"github.com/AmandaIsrael/faster-cep-api/internal/infra/handlers"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/logging"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/ratelimit"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 func main() {
-	config := configs.Load()
-	server := setupServer(config)
-	http.ListenAndServe(":"+config.Port, server)
+	config := configs.Load(gateway.ProviderNames()...)
+	logging.Setup(config)
+	if err := run(config); err != nil {
+		slog.Error("servidor encerrado com erro", "error", err)
+		os.Exit(1)
+	}
 }
 
-func setupServer(config *configs.Config) http.Handler {
-	cepGateway := gateway.NewCEPGateway(config)
-	cepHandler := handlers.NewCepHandler(cepGateway, config)
+func run(config *configs.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("erro ao exportar spans pendentes", "error", err)
+		}
+	}()
+
+	handler, err := setupServer(config)
+	if err != nil {
+		return err
+	}
+	server := newHTTPServer(config, handler)
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("servidor escutando", "addr", listener.Addr().String())
+	return serve(ctx, server, listener, config.ShutdownTimeout)
+}
+
+func newHTTPServer(config *configs.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + config.Port,
+		Handler:      handler,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		IdleTimeout:  config.IdleTimeout,
+	}
+}
+
+// serve runs server until the listener fails or ctx is cancelled, in which
+// case in-flight requests get up to grace to finish before the server exits.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, grace time.Duration) error {
+	chanError := make(chan error, 1)
+	go func() {
+		chanError <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-chanError:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("servidor encerrando", "grace", grace)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-chanError; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func setupServer(config *configs.Config) (http.Handler, error) {
+	cepGateway, err := gateway.NewCEPGateway(config)
+	if err != nil {
+		return nil, err
+	}
+	cepCache, err := cache.New(config)
+	if err != nil {
+		return nil, err
+	}
+	cepHandler := handlers.NewCepHandler(cepGateway, cepCache, config)
+	if err := cepHandler.CheckConfig(); err != nil {
+		return nil, err
+	}
+	healthHandler := handlers.NewHealthHandler(cepGateway, config)
 
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Get("/{cep}", cepHandler.GetCEP)
+	if config.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
+	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware)
+	r.Use(handlers.Recoverer)
+	r.NotFound(handlers.NotFound)
+	r.MethodNotAllowed(handlers.MethodNotAllowed)
+	r.Get("/healthz", healthHandler.Liveness)
+	r.Get("/readyz", healthHandler.Readiness)
+	r.Get("/status/providers", healthHandler.ProviderStatus)
+	r.Handle("/metrics", metrics.Handler())
+	r.Group(func(r chi.Router) {
+		r.Use(metrics.Middleware)
+		r.Use(ratelimit.Middleware(config, handlers.RateLimited))
+		r.Get("/{cep}", cepHandler.GetCEP)
+		r.Get("/v2/{cep}", cepHandler.GetCEPV2)
+		r.Get("/compare/{cep}", cepHandler.CompareCEP)
+		r.Post("/ceps", cepHandler.BatchCEP)
+		r.Post("/ceps/stream", cepHandler.StreamCEP)
+	})
 
-	return r
+	return r, nil
 }
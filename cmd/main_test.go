@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/handlers"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,12 +27,29 @@ func TestSetupServer(t *testing.T) {
 		Port:         "8000",
 	}
 
-	server := setupServer(config)
+	server, err := setupServer(config)
+	assert.NoError(t, err)
 
 	assert.NotNil(t, server)
 	assert.Implements(t, (*http.Handler)(nil), server)
 }
 
+func TestSetupServerRejectsInvalidLookupConfig(t *testing.T) {
+	_, err := setupServer(&configs.Config{LookupStrategy: "fastest"})
+	assert.ErrorContains(t, err, "LOOKUP_STRATEGY")
+
+	_, err = setupServer(&configs.Config{LookupOrder: []string{"OpenCEP"}})
+	assert.ErrorContains(t, err, "LOOKUP_ORDER")
+
+	_, err = setupServer(&configs.Config{LookupStrategy: "failover", LookupOrder: []string{"ViaCEP"}})
+	assert.NoError(t, err)
+}
+
+func TestSetupServerRejectsUnknownCacheBackend(t *testing.T) {
+	_, err := setupServer(&configs.Config{CacheBackend: "redsi"})
+	assert.ErrorContains(t, err, "CACHE_BACKEND")
+}
+
 func TestSetupServerRoutes(t *testing.T) {
 	config := &configs.Config{
 		BrasilAPIURL: "https://brasilapi.com.br/api/cep/v1/%s",
@@ -32,7 +58,8 @@ func TestSetupServerRoutes(t *testing.T) {
 		Port:         "8000",
 	}
 
-	server := setupServer(config)
+	server, err := setupServer(config)
+	assert.NoError(t, err)
 
 	req := httptest.NewRequest("GET", "/01310100", nil)
 	recorder := httptest.NewRecorder()
@@ -40,3 +67,214 @@ func TestSetupServerRoutes(t *testing.T) {
 	server.ServeHTTP(recorder, req)
 	assert.NotEqual(t, http.StatusNotFound, recorder.Code)
 }
+
+func TestSetupServerV2Route(t *testing.T) {
+	config := &configs.Config{
+		BrasilAPIURL: "https://brasilapi.com.br/api/cep/v1/%s",
+		ViaCEPURL:    "http://viacep.com.br/ws/%s/json/",
+		Timeout:      time.Millisecond * 100,
+		Port:         "8000",
+	}
+
+	server, err := setupServer(config)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/v2/01310100", nil)
+	recorder := httptest.NewRecorder()
+
+	server.ServeHTTP(recorder, req)
+	assert.NotEqual(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestSetupServerCompareRoute(t *testing.T) {
+	server, err := setupServer(&configs.Config{Timeout: time.Millisecond * 100})
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/compare/abc", nil)
+	recorder := httptest.NewRecorder()
+
+	server.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), handlers.CodeInvalidCEP)
+}
+
+func TestSetupServerRateLimitsCEPRoutes(t *testing.T) {
+	server, err := setupServer(&configs.Config{
+		Timeout:           time.Millisecond * 100,
+		RateLimitRPS:      0.001,
+		RateLimitBurst:    1,
+		TrustProxyHeaders: true,
+	})
+	assert.NoError(t, err)
+
+	request := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request("/abc", "203.0.113.1").Code)
+
+	limited := request("/abc", "203.0.113.1")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.NotEmpty(t, limited.Header().Get("Retry-After"))
+	assert.Equal(t, "1", limited.Header().Get("RateLimit-Limit"))
+	assert.Contains(t, limited.Body.String(), handlers.CodeRateLimited)
+
+	assert.Equal(t, http.StatusBadRequest, request("/abc", "203.0.113.2").Code)
+	assert.Equal(t, http.StatusOK, request("/healthz", "203.0.113.1").Code)
+}
+
+func TestSetupServerCountsEveryLookupRoute(t *testing.T) {
+	server, err := setupServer(&configs.Config{Timeout: time.Millisecond * 100, BatchMaxSize: 10})
+	assert.NoError(t, err)
+
+	badRequestBefore := testutil.ToFloat64(metrics.Requests.WithLabelValues("400"))
+	okBefore := testutil.ToFloat64(metrics.Requests.WithLabelValues("200"))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/abc", nil),
+		httptest.NewRequest("GET", "/v2/abc", nil),
+		httptest.NewRequest("GET", "/compare/abc", nil),
+		httptest.NewRequest("POST", "/ceps", strings.NewReader("not json")),
+	} {
+		server.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/ceps", strings.NewReader("[]")))
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, badRequestBefore+4, testutil.ToFloat64(metrics.Requests.WithLabelValues("400")))
+	assert.Equal(t, okBefore+1, testutil.ToFloat64(metrics.Requests.WithLabelValues("200")))
+}
+
+func TestNewHTTPServer(t *testing.T) {
+	config := &configs.Config{
+		Port:         "8000",
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Second * 2,
+		IdleTimeout:  time.Second * 3,
+	}
+	handler := http.NewServeMux()
+
+	server := newHTTPServer(config, handler)
+
+	assert.Equal(t, ":8000", server.Addr)
+	assert.Equal(t, handler, server.Handler)
+	assert.Equal(t, time.Second, server.ReadTimeout)
+	assert.Equal(t, time.Second*2, server.WriteTimeout)
+	assert.Equal(t, time.Second*3, server.IdleTimeout)
+}
+
+func TestServeDrainsInFlightRequestsOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(time.Millisecond * 100)
+		w.Write([]byte("ok"))
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chanServe := make(chan error, 1)
+	go func() {
+		chanServe <- serve(ctx, server, listener, time.Second)
+	}()
+
+	chanResponse := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			chanResponse <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		chanResponse <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, "ok", <-chanResponse)
+	assert.NoError(t, <-chanServe)
+}
+
+func TestServeReturnsListenerError(t *testing.T) {
+	server := &http.Server{Handler: http.NewServeMux()}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	listener.Close()
+
+	err = serve(context.Background(), server, listener, time.Second)
+
+	assert.Error(t, err)
+}
+
+func TestRunFailsWhenPortIsTaken(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	config := &configs.Config{Port: port}
+
+	assert.Error(t, run(config))
+}
+
+func TestSetupServerHealthRoutes(t *testing.T) {
+	config := &configs.Config{
+		BrasilAPIURL: "https://brasilapi.com.br/api/cep/v1/%s",
+		ViaCEPURL:    "http://viacep.com.br/ws/%s/json/",
+		Timeout:      time.Second,
+	}
+
+	server, err := setupServer(config)
+	assert.NoError(t, err)
+
+	for _, path := range []string{"/healthz", "/status/providers", "/metrics"} {
+		req := httptest.NewRequest("GET", path, nil)
+		recorder := httptest.NewRecorder()
+
+		server.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code, path)
+	}
+}
+
+func TestSetupServerErrorsUseJSONEnvelope(t *testing.T) {
+	config := &configs.Config{
+		BrasilAPIURL: "https://brasilapi.com.br/api/cep/v1/%s",
+		ViaCEPURL:    "http://viacep.com.br/ws/%s/json/",
+		Timeout:      time.Second,
+	}
+
+	server, err := setupServer(config)
+	assert.NoError(t, err)
+
+	cases := map[string]struct {
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		"invalid CEP":        {"GET", "/00000000", http.StatusBadRequest, handlers.CodeInvalidCEP},
+		"unknown route":      {"GET", "/v2/01310100/extra", http.StatusNotFound, handlers.CodeRouteNotFound},
+		"method not allowed": {"DELETE", "/01310100", http.StatusMethodNotAllowed, handlers.CodeMethodNotAllowed},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+
+			var response dto.ErrorResponse
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, tc.status, recorder.Code)
+			assert.Equal(t, tc.code, response.Error.Code)
+			assert.NotEmpty(t, response.Error.RequestID)
+			assert.Equal(t, recorder.Header().Get("X-Request-Id"), response.Error.RequestID)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package configs
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,14 +12,181 @@ type Config struct {
 	ViaCEPURL    string
 	Timeout      time.Duration
 	Port         string
+
+	LookupStrategy string
+	LookupOrder    []string
+	HedgeDelay     time.Duration
+
+	MergePreferredProvider string
+
+	ProviderRetries      int
+	ProviderRetryBackoff time.Duration
+	BreakerThreshold     int
+	BreakerCooldown      time.Duration
+
+	Providers map[string]ProviderConfig
+
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	CacheBackend     string
+	CacheSize        int
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	RedisTimeout  time.Duration
+
+	BatchMaxSize     int
+	BatchConcurrency int
+
+	RateLimitRPS      float64
+	RateLimitBurst    int
+	RateLimitKeyRPS   float64
+	RateLimitKeyBurst int
+	RateLimitAPIKeys  []string
+	TrustProxyHeaders bool
+
+	StreamConcurrency int
+
+	ReadinessTimeout time.Duration
+	ReadinessMaxAge  time.Duration
+
+	TracingExporter    string
+	TracingSampleRatio float64
+
+	LogLevel  string
+	LogFormat string
+}
+
+// ProviderConfig holds the settings of one upstream provider, read from
+// environment variables prefixed with its upper-cased name, such as
+// BRASILAPI_RATE_LIMIT.
+type ProviderConfig struct {
+	Timeout       time.Duration
+	RateLimit     float64
+	Burst         int
+	MaxConcurrent int
+	HTTP          HTTPClientConfig
+}
+
+// HTTPClientConfig tunes the HTTP client of one upstream provider.
+type HTTPClientConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	HTTP2               bool
+	ProxyURL            string
+	CABundle            string
 }
 
-func Load() *Config {
+// Load reads the configuration from the environment, including the
+// settings of each named provider.
+func Load(providers ...string) *Config {
+	sharedHTTP := loadHTTPClient("", HTTPClientConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+		DialTimeout:         2 * time.Second,
+		TLSHandshakeTimeout: 2 * time.Second,
+		HTTP2:               true,
+	})
+
 	return &Config{
 		BrasilAPIURL: getEnv("BRASILAPI_URL", "https://brasilapi.com.br/api/cep/v1/%s"),
 		ViaCEPURL:    getEnv("VIACEP_URL", "http://viacep.com.br/ws/%s/json/"),
 		Timeout:      getDuration("TIMEOUT", time.Second),
 		Port:         getEnv("PORT", "8080"),
+
+		LookupStrategy: getEnv("LOOKUP_STRATEGY", "race"),
+		LookupOrder:    getList("LOOKUP_ORDER"),
+		HedgeDelay:     getDuration("HEDGE_DELAY", 100*time.Millisecond),
+
+		MergePreferredProvider: getEnv("MERGE_PREFERRED_PROVIDER", ""),
+
+		ProviderRetries:      getInt("PROVIDER_RETRIES", 1),
+		ProviderRetryBackoff: getDuration("PROVIDER_RETRY_BACKOFF", 50*time.Millisecond),
+		BreakerThreshold:     getInt("BREAKER_THRESHOLD", 5),
+		BreakerCooldown:      getDuration("BREAKER_COOLDOWN", 30*time.Second),
+
+		Providers: loadProviders(providers, sharedHTTP),
+
+		ReadTimeout:     getDuration("READ_TIMEOUT", 5*time.Second),
+		WriteTimeout:    getDuration("WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:     getDuration("IDLE_TIMEOUT", 60*time.Second),
+		ShutdownTimeout: getDuration("SHUTDOWN_TIMEOUT", 10*time.Second),
+
+		CacheBackend:     getEnv("CACHE_BACKEND", "memory"),
+		CacheSize:        getInt("CACHE_SIZE", 10000),
+		CacheTTL:         getDuration("CACHE_TTL", 24*time.Hour),
+		CacheNegativeTTL: getDuration("CACHE_NEGATIVE_TTL", 10*time.Minute),
+
+		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword: getEnv("REDIS_PASSWORD", ""),
+		RedisDB:       getInt("REDIS_DB", 0),
+		RedisTimeout:  getDuration("REDIS_TIMEOUT", 100*time.Millisecond),
+
+		BatchMaxSize:     getInt("BATCH_MAX_SIZE", 1000),
+		BatchConcurrency: getInt("BATCH_CONCURRENCY", 10),
+
+		RateLimitRPS:      getFloat("RATE_LIMIT_RPS", 0),
+		RateLimitBurst:    getInt("RATE_LIMIT_BURST", 20),
+		RateLimitKeyRPS:   getFloat("RATE_LIMIT_KEY_RPS", 0),
+		RateLimitKeyBurst: getInt("RATE_LIMIT_KEY_BURST", 100),
+		RateLimitAPIKeys:  getList("RATE_LIMIT_API_KEYS"),
+		TrustProxyHeaders: getBool("TRUST_PROXY_HEADERS", false),
+
+		StreamConcurrency: getInt("STREAM_CONCURRENCY", 20),
+
+		ReadinessTimeout: getDuration("READINESS_TIMEOUT", 2*time.Second),
+		ReadinessMaxAge:  getDuration("READINESS_MAX_AGE", 30*time.Second),
+
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingSampleRatio: getFloat("TRACING_SAMPLE_RATIO", 1),
+
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+		LogFormat: getEnv("LOG_FORMAT", "json"),
+	}
+}
+
+// loadProviders reads the <NAME>_* settings of each provider in names. Its
+// <NAME>_HTTP_* settings fall back to the shared HTTP_* ones in http.
+func loadProviders(names []string, http HTTPClientConfig) map[string]ProviderConfig {
+	providers := make(map[string]ProviderConfig, len(names))
+	for _, name := range names {
+		prefix := strings.ToUpper(name) + "_"
+		providers[name] = ProviderConfig{
+			Timeout:       getDuration(prefix+"TIMEOUT", 0),
+			RateLimit:     getFloat(prefix+"RATE_LIMIT", 0),
+			Burst:         getInt(prefix+"BURST", 10),
+			MaxConcurrent: getInt(prefix+"MAX_CONCURRENT", 0),
+			HTTP:          loadHTTPClient(prefix, http),
+		}
+	}
+	return providers
+}
+
+// loadHTTPClient reads the HTTP_* settings under prefix, falling back to
+// defaults for the ones that are unset.
+func loadHTTPClient(prefix string, defaults HTTPClientConfig) HTTPClientConfig {
+	return HTTPClientConfig{
+		MaxIdleConns:        getInt(prefix+"HTTP_MAX_IDLE_CONNS", defaults.MaxIdleConns),
+		MaxIdleConnsPerHost: getInt(prefix+"HTTP_MAX_IDLE_CONNS_PER_HOST", defaults.MaxIdleConnsPerHost),
+		IdleConnTimeout:     getDuration(prefix+"HTTP_IDLE_CONN_TIMEOUT", defaults.IdleConnTimeout),
+		KeepAlive:           getDuration(prefix+"HTTP_KEEP_ALIVE", defaults.KeepAlive),
+		DialTimeout:         getDuration(prefix+"HTTP_DIAL_TIMEOUT", defaults.DialTimeout),
+		TLSHandshakeTimeout: getDuration(prefix+"HTTP_TLS_HANDSHAKE_TIMEOUT", defaults.TLSHandshakeTimeout),
+		HTTP2:               getBool(prefix+"HTTP2_ENABLED", defaults.HTTP2),
+		ProxyURL:            getEnv(prefix+"HTTP_PROXY_URL", defaults.ProxyURL),
+		CABundle:            getEnv(prefix+"HTTP_CA_BUNDLE", defaults.CABundle),
 	}
 }
 
@@ -40,3 +209,51 @@ func getDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return duration
 }
+
+func getInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return number
+}
+
+func getFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return number
+}
+
+func getBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// getList splits a comma-separated value, dropping blanks around and between
+// the items.
+func getList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
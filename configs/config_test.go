@@ -11,12 +11,59 @@ import (
 func TestLoadConfigWithDefaults(t *testing.T) {
 	os.Clearenv()
 
-	config := Load()
+	config := Load("BrasilAPI")
 
 	assert.Equal(t, "https://brasilapi.com.br/api/cep/v1/%s", config.BrasilAPIURL)
 	assert.Equal(t, "http://viacep.com.br/ws/%s/json/", config.ViaCEPURL)
 	assert.Equal(t, time.Second, config.Timeout)
 	assert.Equal(t, "8080", config.Port)
+	assert.Equal(t, "race", config.LookupStrategy)
+	assert.Empty(t, config.LookupOrder)
+	assert.Equal(t, 100*time.Millisecond, config.HedgeDelay)
+	assert.Equal(t, "", config.MergePreferredProvider)
+	assert.Equal(t, 1, config.ProviderRetries)
+	assert.Equal(t, 50*time.Millisecond, config.ProviderRetryBackoff)
+	assert.Equal(t, 5, config.BreakerThreshold)
+	assert.Equal(t, 30*time.Second, config.BreakerCooldown)
+	assert.Equal(t, map[string]ProviderConfig{"BrasilAPI": {
+		Burst: 10,
+		HTTP: HTTPClientConfig{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 32,
+			IdleConnTimeout:     90 * time.Second,
+			KeepAlive:           30 * time.Second,
+			DialTimeout:         2 * time.Second,
+			TLSHandshakeTimeout: 2 * time.Second,
+			HTTP2:               true,
+		},
+	}}, config.Providers)
+	assert.Equal(t, 5*time.Second, config.ReadTimeout)
+	assert.Equal(t, 30*time.Second, config.WriteTimeout)
+	assert.Equal(t, 60*time.Second, config.IdleTimeout)
+	assert.Equal(t, 10*time.Second, config.ShutdownTimeout)
+	assert.Equal(t, "memory", config.CacheBackend)
+	assert.Equal(t, 10000, config.CacheSize)
+	assert.Equal(t, 24*time.Hour, config.CacheTTL)
+	assert.Equal(t, 10*time.Minute, config.CacheNegativeTTL)
+	assert.Equal(t, "localhost:6379", config.RedisAddr)
+	assert.Equal(t, "", config.RedisPassword)
+	assert.Equal(t, 0, config.RedisDB)
+	assert.Equal(t, 100*time.Millisecond, config.RedisTimeout)
+	assert.Equal(t, 1000, config.BatchMaxSize)
+	assert.Equal(t, 10, config.BatchConcurrency)
+	assert.Equal(t, 0.0, config.RateLimitRPS)
+	assert.Equal(t, 20, config.RateLimitBurst)
+	assert.Equal(t, 0.0, config.RateLimitKeyRPS)
+	assert.Equal(t, 100, config.RateLimitKeyBurst)
+	assert.Empty(t, config.RateLimitAPIKeys)
+	assert.False(t, config.TrustProxyHeaders)
+	assert.Equal(t, 20, config.StreamConcurrency)
+	assert.Equal(t, 2*time.Second, config.ReadinessTimeout)
+	assert.Equal(t, 30*time.Second, config.ReadinessMaxAge)
+	assert.Equal(t, "none", config.TracingExporter)
+	assert.Equal(t, 1.0, config.TracingSampleRatio)
+	assert.Equal(t, "info", config.LogLevel)
+	assert.Equal(t, "json", config.LogFormat)
 }
 
 func TestLoadConfigWithEnvVars(t *testing.T) {
@@ -24,17 +71,126 @@ func TestLoadConfigWithEnvVars(t *testing.T) {
 	os.Setenv("VIACEP_URL", "https://custom-viacep.com/%s")
 	os.Setenv("TIMEOUT", "5s")
 	os.Setenv("PORT", "3000")
+	os.Setenv("LOOKUP_STRATEGY", "hedge")
+	os.Setenv("LOOKUP_ORDER", "ViaCEP, BrasilAPI")
+	os.Setenv("HEDGE_DELAY", "250ms")
+	os.Setenv("MERGE_PREFERRED_PROVIDER", "ViaCEP")
+	os.Setenv("BRASILAPI_TIMEOUT", "700ms")
+	os.Setenv("VIACEP_TIMEOUT", "900ms")
+	os.Setenv("PROVIDER_RETRIES", "2")
+	os.Setenv("PROVIDER_RETRY_BACKOFF", "10ms")
+	os.Setenv("BREAKER_THRESHOLD", "3")
+	os.Setenv("BREAKER_COOLDOWN", "1m")
+	os.Setenv("BRASILAPI_RATE_LIMIT", "5")
+	os.Setenv("BRASILAPI_BURST", "15")
+	os.Setenv("BRASILAPI_MAX_CONCURRENT", "8")
+	os.Setenv("VIACEP_RATE_LIMIT", "2.5")
+	os.Setenv("VIACEP_BURST", "4")
+	os.Setenv("VIACEP_MAX_CONCURRENT", "3")
+	os.Setenv("HTTP_MAX_IDLE_CONNS", "20")
+	os.Setenv("HTTP_MAX_IDLE_CONNS_PER_HOST", "10")
+	os.Setenv("HTTP_IDLE_CONN_TIMEOUT", "45s")
+	os.Setenv("HTTP_KEEP_ALIVE", "15s")
+	os.Setenv("HTTP_DIAL_TIMEOUT", "1s")
+	os.Setenv("HTTP_TLS_HANDSHAKE_TIMEOUT", "3s")
+	os.Setenv("HTTP2_ENABLED", "false")
+	os.Setenv("HTTP_PROXY_URL", "http://proxy.internal:3128")
+	os.Setenv("HTTP_CA_BUNDLE", "/etc/ssl/corp-ca.pem")
+	os.Setenv("BRASILAPI_HTTP_MAX_IDLE_CONNS_PER_HOST", "64")
+	os.Setenv("BRASILAPI_HTTP_PROXY_URL", "http://egress.internal:3128")
+	os.Setenv("VIACEP_HTTP2_ENABLED", "true")
+	os.Setenv("READ_TIMEOUT", "2s")
+	os.Setenv("WRITE_TIMEOUT", "3s")
+	os.Setenv("IDLE_TIMEOUT", "4s")
+	os.Setenv("SHUTDOWN_TIMEOUT", "20s")
+	os.Setenv("CACHE_BACKEND", "redis")
+	os.Setenv("CACHE_SIZE", "500")
+	os.Setenv("CACHE_TTL", "1h")
+	os.Setenv("CACHE_NEGATIVE_TTL", "30s")
+	os.Setenv("REDIS_ADDR", "redis:6379")
+	os.Setenv("REDIS_PASSWORD", "secret")
+	os.Setenv("REDIS_DB", "2")
+	os.Setenv("REDIS_TIMEOUT", "50ms")
+	os.Setenv("BATCH_MAX_SIZE", "50")
+	os.Setenv("BATCH_CONCURRENCY", "4")
+	os.Setenv("RATE_LIMIT_RPS", "2.5")
+	os.Setenv("RATE_LIMIT_BURST", "5")
+	os.Setenv("RATE_LIMIT_KEY_RPS", "50")
+	os.Setenv("RATE_LIMIT_KEY_BURST", "200")
+	os.Setenv("RATE_LIMIT_API_KEYS", "partner-a, partner-b")
+	os.Setenv("TRUST_PROXY_HEADERS", "true")
+	os.Setenv("STREAM_CONCURRENCY", "8")
+	os.Setenv("READINESS_TIMEOUT", "500ms")
+	os.Setenv("READINESS_MAX_AGE", "1m")
+	os.Setenv("TRACING_EXPORTER", "otlp")
+	os.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	os.Setenv("LOG_LEVEL", "debug")
+	os.Setenv("LOG_FORMAT", "text")
 
 	defer func() {
 		os.Clearenv()
 	}()
 
-	config := Load()
+	config := Load("BrasilAPI", "ViaCEP", "OpenCEP")
 
 	assert.Equal(t, "https://custom-brasil-api.com/%s", config.BrasilAPIURL)
 	assert.Equal(t, "https://custom-viacep.com/%s", config.ViaCEPURL)
 	assert.Equal(t, time.Second*5, config.Timeout)
 	assert.Equal(t, "3000", config.Port)
+	assert.Equal(t, "hedge", config.LookupStrategy)
+	assert.Equal(t, []string{"ViaCEP", "BrasilAPI"}, config.LookupOrder)
+	assert.Equal(t, 250*time.Millisecond, config.HedgeDelay)
+	assert.Equal(t, "ViaCEP", config.MergePreferredProvider)
+	assert.Equal(t, 2, config.ProviderRetries)
+	assert.Equal(t, 10*time.Millisecond, config.ProviderRetryBackoff)
+	assert.Equal(t, 3, config.BreakerThreshold)
+	assert.Equal(t, time.Minute, config.BreakerCooldown)
+	viaCEPHTTP := HTTPClientConfig{
+		MaxIdleConns:        20,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     45 * time.Second,
+		KeepAlive:           15 * time.Second,
+		DialTimeout:         time.Second,
+		TLSHandshakeTimeout: 3 * time.Second,
+		HTTP2:               true,
+		ProxyURL:            "http://proxy.internal:3128",
+		CABundle:            "/etc/ssl/corp-ca.pem",
+	}
+	brasilAPIHTTP := viaCEPHTTP
+	brasilAPIHTTP.MaxIdleConnsPerHost = 64
+	brasilAPIHTTP.HTTP2 = false
+	brasilAPIHTTP.ProxyURL = "http://egress.internal:3128"
+	assert.Equal(t, ProviderConfig{Timeout: 700 * time.Millisecond, RateLimit: 5, Burst: 15, MaxConcurrent: 8, HTTP: brasilAPIHTTP}, config.Providers["BrasilAPI"])
+	assert.Equal(t, ProviderConfig{Timeout: 900 * time.Millisecond, RateLimit: 2.5, Burst: 4, MaxConcurrent: 3, HTTP: viaCEPHTTP}, config.Providers["ViaCEP"])
+	assert.Equal(t, 10, config.Providers["OpenCEP"].Burst)
+	assert.False(t, config.Providers["OpenCEP"].HTTP.HTTP2)
+	assert.Equal(t, 2*time.Second, config.ReadTimeout)
+	assert.Equal(t, 3*time.Second, config.WriteTimeout)
+	assert.Equal(t, 4*time.Second, config.IdleTimeout)
+	assert.Equal(t, 20*time.Second, config.ShutdownTimeout)
+	assert.Equal(t, "redis", config.CacheBackend)
+	assert.Equal(t, 500, config.CacheSize)
+	assert.Equal(t, time.Hour, config.CacheTTL)
+	assert.Equal(t, 30*time.Second, config.CacheNegativeTTL)
+	assert.Equal(t, "redis:6379", config.RedisAddr)
+	assert.Equal(t, "secret", config.RedisPassword)
+	assert.Equal(t, 2, config.RedisDB)
+	assert.Equal(t, 50*time.Millisecond, config.RedisTimeout)
+	assert.Equal(t, 50, config.BatchMaxSize)
+	assert.Equal(t, 4, config.BatchConcurrency)
+	assert.Equal(t, 2.5, config.RateLimitRPS)
+	assert.Equal(t, 5, config.RateLimitBurst)
+	assert.Equal(t, 50.0, config.RateLimitKeyRPS)
+	assert.Equal(t, 200, config.RateLimitKeyBurst)
+	assert.Equal(t, []string{"partner-a", "partner-b"}, config.RateLimitAPIKeys)
+	assert.True(t, config.TrustProxyHeaders)
+	assert.Equal(t, 8, config.StreamConcurrency)
+	assert.Equal(t, 500*time.Millisecond, config.ReadinessTimeout)
+	assert.Equal(t, time.Minute, config.ReadinessMaxAge)
+	assert.Equal(t, "otlp", config.TracingExporter)
+	assert.Equal(t, 0.25, config.TracingSampleRatio)
+	assert.Equal(t, "debug", config.LogLevel)
+	assert.Equal(t, "text", config.LogFormat)
 }
 
 func TestGetEnvWithDefaultValue(t *testing.T) {
@@ -88,3 +244,66 @@ func TestGetDurationWithEmptyValue(t *testing.T) {
 
 	assert.Equal(t, time.Minute, result)
 }
+
+func TestGetIntWithDefaultValue(t *testing.T) {
+	os.Clearenv()
+
+	result := getInt("NON_EXISTENT_INT", 42)
+
+	assert.Equal(t, 42, result)
+}
+
+func TestGetIntWithValidValue(t *testing.T) {
+	os.Setenv("TEST_INT", "7")
+	defer os.Unsetenv("TEST_INT")
+
+	result := getInt("TEST_INT", 42)
+
+	assert.Equal(t, 7, result)
+}
+
+func TestGetIntWithInvalidValue(t *testing.T) {
+	os.Setenv("TEST_INT", "seven")
+	defer os.Unsetenv("TEST_INT")
+
+	result := getInt("TEST_INT", 42)
+
+	assert.Equal(t, 42, result)
+}
+
+func TestGetFloatWithValidValue(t *testing.T) {
+	os.Setenv("TEST_FLOAT", "0.5")
+	defer os.Unsetenv("TEST_FLOAT")
+
+	result := getFloat("TEST_FLOAT", 1)
+
+	assert.Equal(t, 0.5, result)
+}
+
+func TestGetFloatWithInvalidValue(t *testing.T) {
+	os.Setenv("TEST_FLOAT", "half")
+	defer os.Unsetenv("TEST_FLOAT")
+
+	result := getFloat("TEST_FLOAT", 1)
+
+	assert.Equal(t, 1.0, result)
+}
+
+func TestGetBool(t *testing.T) {
+	os.Setenv("TEST_BOOL", "false")
+	defer os.Unsetenv("TEST_BOOL")
+
+	assert.False(t, getBool("TEST_BOOL", true))
+	assert.True(t, getBool("NON_EXISTENT_BOOL", true))
+
+	os.Setenv("TEST_BOOL", "maybe")
+	assert.True(t, getBool("TEST_BOOL", true))
+}
+
+func TestGetList(t *testing.T) {
+	os.Setenv("TEST_LIST", " a,b ,, c ")
+	defer os.Unsetenv("TEST_LIST")
+
+	assert.Equal(t, []string{"a", "b", "c"}, getList("TEST_LIST"))
+	assert.Nil(t, getList("NON_EXISTENT_LIST"))
+}
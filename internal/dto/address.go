@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/AmandaIsrael/faster-cep-api/pkg"
+)
+
+// Address is the canonical (v2) response schema. Every provider result is
+// mapped to the same set of fields, whichever provider won the race.
+type Address struct {
+	Cep          string `json:"cep"`
+	Street       string `json:"street"`
+	Complement   string `json:"complement,omitempty"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	StateCode    string `json:"state_code"`
+	State        string `json:"state"`
+	Region       string `json:"region"`
+	Ibge         string `json:"ibge,omitempty"`
+	Ddd          string `json:"ddd,omitempty"`
+	Source       string `json:"source"`
+	UFMismatch   bool   `json:"uf_mismatch,omitempty"`
+
+	Sources map[string]string `json:"sources,omitempty"`
+	Meta    *Meta             `json:"meta,omitempty"`
+}
+
+// NewAddress maps a provider result in the legacy shape to the canonical
+// schema, filling state name and region from the UF table when the provider
+// does not send them, and flagging a UF that contradicts the CEP range.
+func NewAddress(cep *CEP, source string) *Address {
+	address := &Address{
+		Cep:          formatCEP(cep.Cep),
+		Street:       firstNonEmpty(cep.Logradouro, cep.Rua),
+		Complement:   cep.Complemento,
+		Neighborhood: cep.Bairro,
+		City:         firstNonEmpty(cep.Localidade, cep.Cidade),
+		StateCode:    strings.ToUpper(firstNonEmpty(cep.Uf, cep.Estado)),
+		Region:       cep.Regiao,
+		Ibge:         cep.Ibge,
+		Ddd:          cep.Ddd,
+		Source:       source,
+	}
+
+	if state, ok := pkg.LookupState(address.StateCode); ok {
+		address.State = state.Name
+		address.Region = firstNonEmpty(address.Region, state.Region)
+	}
+
+	if cep, err := pkg.NormalizeCEP(address.Cep); err == nil {
+		address.UFMismatch = !pkg.MatchesUF(cep, address.StateCode)
+	}
+
+	return address
+}
+
+func formatCEP(cep string) string {
+	digits := strings.ReplaceAll(cep, "-", "")
+	if len(digits) != 8 {
+		return cep
+	}
+	return digits[:5] + "-" + digits[5:]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAddressFromBrasilAPI(t *testing.T) {
+	cep := &CEP{
+		Cep:     "01310100",
+		Estado:  "SP",
+		Cidade:  "São Paulo",
+		Bairro:  "Bela Vista",
+		Rua:     "Avenida Paulista",
+		Servico: "correios",
+	}
+
+	address := NewAddress(cep, "BrasilAPI")
+
+	assert.Equal(t, &Address{
+		Cep:          "01310-100",
+		Street:       "Avenida Paulista",
+		Neighborhood: "Bela Vista",
+		City:         "São Paulo",
+		StateCode:    "SP",
+		State:        "São Paulo",
+		Region:       "Sudeste",
+		Source:       "BrasilAPI",
+	}, address)
+}
+
+func TestNewAddressFromViaCEP(t *testing.T) {
+	cep := &CEP{
+		Cep:         "01310-100",
+		Logradouro:  "Avenida Paulista",
+		Complemento: "de 612 a 1510 - lado par",
+		Bairro:      "Bela Vista",
+		Localidade:  "São Paulo",
+		Uf:          "SP",
+		Estado:      "SP",
+		Regiao:      "Sudeste",
+		Ibge:        "3550308",
+		Ddd:         "11",
+	}
+
+	address := NewAddress(cep, "ViaCEP")
+
+	assert.Equal(t, &Address{
+		Cep:          "01310-100",
+		Street:       "Avenida Paulista",
+		Complement:   "de 612 a 1510 - lado par",
+		Neighborhood: "Bela Vista",
+		City:         "São Paulo",
+		StateCode:    "SP",
+		State:        "São Paulo",
+		Region:       "Sudeste",
+		Ibge:         "3550308",
+		Ddd:          "11",
+		Source:       "ViaCEP",
+	}, address)
+}
+
+func TestNewAddressUnknownState(t *testing.T) {
+	address := NewAddress(&CEP{Cep: "01310-100", Estado: "XX"}, "BrasilAPI")
+
+	assert.Equal(t, "XX", address.StateCode)
+	assert.Empty(t, address.State)
+	assert.Empty(t, address.Region)
+}
+
+func TestNewAddressFlagsUFMismatch(t *testing.T) {
+	address := NewAddress(&CEP{Cep: "01310-100", Uf: "RJ"}, "ViaCEP")
+
+	assert.True(t, address.UFMismatch)
+	assert.False(t, NewAddress(&CEP{Cep: "01310-100", Uf: "SP"}, "ViaCEP").UFMismatch)
+}
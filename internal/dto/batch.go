@@ -0,0 +1,18 @@
+package dto
+
+// BatchResult is the outcome of one CEP in a batch lookup. Exactly one of
+// Data or Error is set.
+type BatchResult struct {
+	Cep   string      `json:"cep"`
+	Data  *Address    `json:"data,omitempty"`
+	Error *BatchError `json:"error,omitempty"`
+}
+
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type BatchResponse struct {
+	Results []*BatchResult `json:"results"`
+}
@@ -3,6 +3,9 @@ package dto
 type APIResponse struct {
 	Data *CEP
 	Api  string
+	// Sources maps each field of Data to the provider it came from. It is
+	// only set in merge mode.
+	Sources map[string]string
 }
 
 type CEP struct {
@@ -23,3 +26,25 @@ type CEP struct {
 	Siafi       string `json:"siafi,omitempty"`
 	Servico     string `json:"servico,omitempty"`
 }
+
+// CEPResponse is the legacy response body. Sources, the provider of each
+// field, is only set in merge mode, and Meta only when requested.
+type CEPResponse struct {
+	*CEP
+	Sources map[string]string `json:"sources,omitempty"`
+	Meta    *Meta             `json:"meta,omitempty"`
+}
+
+// Meta describes how a response was produced: the provider(s) it came from,
+// how long the lookup took and whether it was served from the cache.
+type Meta struct {
+	Source    string  `json:"source"`
+	LatencyMs float64 `json:"latency_ms"`
+	Cached    bool    `json:"cached"`
+}
+
+// IsEmpty reports whether the payload carries no address data at all, as
+// happens when an upstream answers 200 for a CEP it does not know.
+func (c *CEP) IsEmpty() bool {
+	return c == nil || *c == CEP{}
+}
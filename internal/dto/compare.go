@@ -0,0 +1,73 @@
+package dto
+
+import "github.com/AmandaIsrael/faster-cep-api/pkg"
+
+// ProviderAddress is one provider's answer in a comparison. Exactly one of
+// Data or Error is set.
+type ProviderAddress struct {
+	Provider string           `json:"provider"`
+	Data     *Address         `json:"data,omitempty"`
+	Error    *ProviderFailure `json:"error,omitempty"`
+}
+
+// Disagreement lists, by provider, the values of a field on which the
+// providers differ even after accent, case and spacing normalization.
+type Disagreement struct {
+	Field  string            `json:"field"`
+	Values map[string]string `json:"values"`
+}
+
+// Comparison shows every provider's answer for a CEP side by side.
+// Consensus is true when no field is in disagreement.
+type Comparison struct {
+	Cep           string             `json:"cep"`
+	Providers     []*ProviderAddress `json:"providers"`
+	Disagreements []*Disagreement    `json:"disagreements"`
+	Consensus     bool               `json:"consensus"`
+}
+
+// comparedFields are the Address fields checked for disagreements. State is
+// left out since it is derived from StateCode.
+var comparedFields = []struct {
+	name  string
+	value func(*Address) string
+}{
+	{"street", func(a *Address) string { return a.Street }},
+	{"complement", func(a *Address) string { return a.Complement }},
+	{"neighborhood", func(a *Address) string { return a.Neighborhood }},
+	{"city", func(a *Address) string { return a.City }},
+	{"state_code", func(a *Address) string { return a.StateCode }},
+	{"region", func(a *Address) string { return a.Region }},
+	{"ibge", func(a *Address) string { return a.Ibge }},
+	{"ddd", func(a *Address) string { return a.Ddd }},
+}
+
+// NewComparison compares the providers that returned an address, field by
+// field. A field only one provider fills is not a disagreement.
+func NewComparison(cep string, providers []*ProviderAddress) *Comparison {
+	comparison := &Comparison{
+		Cep:           formatCEP(cep),
+		Providers:     providers,
+		Disagreements: []*Disagreement{},
+	}
+
+	for _, field := range comparedFields {
+		values := make(map[string]string)
+		folded := make(map[string]bool)
+		for _, provider := range providers {
+			if provider.Data == nil {
+				continue
+			}
+			if value := field.value(provider.Data); value != "" {
+				values[provider.Provider] = value
+				folded[pkg.FoldText(value)] = true
+			}
+		}
+		if len(folded) > 1 {
+			comparison.Disagreements = append(comparison.Disagreements, &Disagreement{Field: field.name, Values: values})
+		}
+	}
+
+	comparison.Consensus = len(comparison.Disagreements) == 0
+	return comparison
+}
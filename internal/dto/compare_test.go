@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewComparisonIgnoresAccentsAndCase(t *testing.T) {
+	providers := []*ProviderAddress{
+		{Provider: "BrasilAPI", Data: &Address{Street: "Avenida São João", City: "SÃO PAULO", StateCode: "SP"}},
+		{Provider: "ViaCEP", Data: &Address{Street: "Avenida Sao  Joao", City: "São Paulo", StateCode: "SP", Ibge: "3550308"}},
+	}
+
+	comparison := NewComparison("01032000", providers)
+
+	assert.Equal(t, "01032-000", comparison.Cep)
+	assert.True(t, comparison.Consensus)
+	assert.Empty(t, comparison.Disagreements)
+	assert.Equal(t, providers, comparison.Providers)
+}
+
+func TestNewComparisonReportsDisagreements(t *testing.T) {
+	providers := []*ProviderAddress{
+		{Provider: "BrasilAPI", Data: &Address{Street: "Rua Vitorino Carmilo", Neighborhood: "Campos Elíseos", City: "São Paulo"}},
+		{Provider: "ViaCEP", Data: &Address{Street: "Rua Vitorino Camilo", Neighborhood: "Campos Elíseos", City: "São Paulo"}},
+		{Provider: "OpenCEP", Error: &ProviderFailure{Provider: "OpenCEP", Type: "timeout", Error: "context deadline exceeded"}},
+	}
+
+	comparison := NewComparison("01153000", providers)
+
+	assert.False(t, comparison.Consensus)
+	assert.Equal(t, []*Disagreement{{
+		Field: "street",
+		Values: map[string]string{
+			"BrasilAPI": "Rua Vitorino Carmilo",
+			"ViaCEP":    "Rua Vitorino Camilo",
+		},
+	}}, comparison.Disagreements)
+}
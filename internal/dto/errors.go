@@ -0,0 +1,22 @@
+package dto
+
+// ProviderFailure is why one provider could not resolve a CEP.
+type ProviderFailure struct {
+	Provider string `json:"provider"`
+	Type     string `json:"type"`
+	Error    string `json:"error"`
+}
+
+// ErrorResponse is the body of every error answered by the API.
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+// APIError carries a stable, machine-readable Code alongside a human
+// Message. Providers is only set for ALL_PROVIDERS_FAILED.
+type APIError struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"request_id,omitempty"`
+	Providers []ProviderFailure `json:"providers,omitempty"`
+}
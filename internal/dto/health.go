@@ -0,0 +1,10 @@
+package dto
+
+type HealthStatus struct {
+	Status    string            `json:"status"`
+	Providers map[string]string `json:"providers,omitempty"`
+}
+
+type ProvidersStatusResponse struct {
+	Providers []ProviderStatus `json:"providers"`
+}
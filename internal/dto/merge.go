@@ -0,0 +1,114 @@
+package dto
+
+import (
+	"slices"
+	"strings"
+)
+
+type cepField struct {
+	name  string
+	value *string
+}
+
+// fields lists c's fields by JSON name, in declaration order.
+func (c *CEP) fields() []cepField {
+	return []cepField{
+		{"cep", &c.Cep},
+		{"logradouro", &c.Logradouro},
+		{"complemento", &c.Complemento},
+		{"unidade", &c.Unidade},
+		{"bairro", &c.Bairro},
+		{"rua", &c.Rua},
+		{"localidade", &c.Localidade},
+		{"uf", &c.Uf},
+		{"cidade", &c.Cidade},
+		{"estado", &c.Estado},
+		{"regiao", &c.Regiao},
+		{"ibge", &c.Ibge},
+		{"gia", &c.Gia},
+		{"ddd", &c.Ddd},
+		{"siafi", &c.Siafi},
+		{"servico", &c.Servico},
+	}
+}
+
+// MergeCEPs combines provider results field by field. Each field is taken
+// from the first result, in the order given, that fills it, so callers put
+// the preferred provider first. Legacy fields that are aliases of one
+// Address field, such as logradouro and rua, are taken together from the
+// first result that fills any of them, so providers spelling the same field
+// differently still conflict. Sources maps the JSON name of every filled
+// field to its provider, and Api lists the providers that contributed.
+func MergeCEPs(results []*APIResponse) *APIResponse {
+	merged := &APIResponse{Data: &CEP{}, Sources: make(map[string]string)}
+	target := merged.Data.fields()
+	owners := make(map[string]int)
+
+	var contributors []string
+	for i, result := range results {
+		contributed := false
+		for j, field := range result.Data.fields() {
+			if *field.value == "" || *target[j].value != "" {
+				continue
+			}
+			group := aliasGroup(field.name)
+			if owner, ok := owners[group]; ok && owner != i {
+				continue
+			}
+			owners[group] = i
+			*target[j].value = *field.value
+			merged.Sources[field.name] = result.Api
+			contributed = true
+		}
+		if contributed {
+			contributors = append(contributors, result.Api)
+		}
+	}
+	merged.Api = strings.Join(contributors, ",")
+	return merged
+}
+
+// aliasGroup is the Address field a legacy field is read into, or the field
+// itself when it has no aliases.
+func aliasGroup(field string) string {
+	for _, source := range addressSources {
+		if len(source.fields) > 1 && slices.Contains(source.fields, field) {
+			return source.name
+		}
+	}
+	return field
+}
+
+// addressSources lists, for each Address field that comes from a provider,
+// the legacy fields it is read from, in NewAddress's order of preference.
+var addressSources = []struct {
+	name   string
+	fields []string
+}{
+	{"cep", []string{"cep"}},
+	{"street", []string{"logradouro", "rua"}},
+	{"complement", []string{"complemento"}},
+	{"neighborhood", []string{"bairro"}},
+	{"city", []string{"localidade", "cidade"}},
+	{"state_code", []string{"uf", "estado"}},
+	{"region", []string{"regiao"}},
+	{"ibge", []string{"ibge"}},
+	{"ddd", []string{"ddd"}},
+}
+
+// NewMergedAddress maps a result of MergeCEPs to the canonical schema,
+// translating its provenance to the canonical field names. Fields filled
+// from the UF table, such as state, have no provider and are left out.
+func NewMergedAddress(res *APIResponse) *Address {
+	address := NewAddress(res.Data, res.Api)
+	address.Sources = make(map[string]string)
+	for _, source := range addressSources {
+		for _, field := range source.fields {
+			if provider, ok := res.Sources[field]; ok {
+				address.Sources[source.name] = provider
+				break
+			}
+		}
+	}
+	return address
+}
@@ -0,0 +1,110 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeCEPsCombinesFieldsWithProvenance(t *testing.T) {
+	brasilAPI := &APIResponse{Api: "BrasilAPI", Data: &CEP{
+		Cep:     "01310100",
+		Estado:  "SP",
+		Cidade:  "São Paulo",
+		Bairro:  "Bela Vista",
+		Rua:     "Avenida Paulista",
+		Servico: "correios",
+	}}
+	viaCEP := &APIResponse{Api: "ViaCEP", Data: &CEP{
+		Cep:        "01310-100",
+		Logradouro: "Avenida Paulista",
+		Bairro:     "Bela Vista - SP",
+		Localidade: "São Paulo",
+		Uf:         "SP",
+		Ibge:       "3550308",
+		Ddd:        "11",
+	}}
+
+	merged := MergeCEPs([]*APIResponse{brasilAPI, viaCEP})
+
+	assert.Equal(t, "BrasilAPI,ViaCEP", merged.Api)
+	assert.Equal(t, "01310100", merged.Data.Cep)
+	assert.Equal(t, "Bela Vista", merged.Data.Bairro)
+	assert.Equal(t, "correios", merged.Data.Servico)
+	assert.Equal(t, "3550308", merged.Data.Ibge)
+	assert.Equal(t, "11", merged.Data.Ddd)
+	assert.Equal(t, "Avenida Paulista", merged.Data.Rua)
+	assert.Empty(t, merged.Data.Logradouro)
+	assert.Empty(t, merged.Data.Localidade)
+	assert.Empty(t, merged.Data.Uf)
+	assert.Equal(t, map[string]string{
+		"cep":     "BrasilAPI",
+		"estado":  "BrasilAPI",
+		"cidade":  "BrasilAPI",
+		"bairro":  "BrasilAPI",
+		"rua":     "BrasilAPI",
+		"servico": "BrasilAPI",
+		"ibge":    "ViaCEP",
+		"ddd":     "ViaCEP",
+	}, merged.Sources)
+
+	preferViaCEP := MergeCEPs([]*APIResponse{viaCEP, brasilAPI})
+	assert.Equal(t, "ViaCEP,BrasilAPI", preferViaCEP.Api)
+	assert.Equal(t, "Bela Vista - SP", preferViaCEP.Data.Bairro)
+	assert.Equal(t, "ViaCEP", preferViaCEP.Sources["bairro"])
+	assert.Equal(t, "ViaCEP", preferViaCEP.Sources["logradouro"])
+	assert.Empty(t, preferViaCEP.Data.Rua)
+	assert.Empty(t, preferViaCEP.Data.Cidade)
+	assert.Empty(t, preferViaCEP.Data.Estado)
+}
+
+func TestMergeCEPsPreferredProviderWinsAliasedFields(t *testing.T) {
+	brasilAPI := &APIResponse{Api: "BrasilAPI", Data: &CEP{Cep: "01310100", Rua: "Av. Paulista", Cidade: "Sao Paulo", Estado: "SP"}}
+	viaCEP := &APIResponse{Api: "ViaCEP", Data: &CEP{Cep: "01310-100", Logradouro: "Avenida Paulista", Localidade: "São Paulo", Uf: "RJ"}}
+
+	for preferred, results := range map[string][]*APIResponse{
+		"BrasilAPI": {brasilAPI, viaCEP},
+		"ViaCEP":    {viaCEP, brasilAPI},
+	} {
+		address := NewMergedAddress(MergeCEPs(results))
+
+		source := results[0].Data
+		assert.Equal(t, preferred, address.Sources["street"])
+		assert.Equal(t, preferred, address.Sources["city"])
+		assert.Equal(t, preferred, address.Sources["state_code"])
+		assert.Equal(t, source.Logradouro+source.Rua, address.Street)
+		assert.Equal(t, source.Localidade+source.Cidade, address.City)
+		assert.Equal(t, source.Uf+source.Estado, address.StateCode)
+	}
+}
+
+func TestMergeCEPsLeavesOutProvidersThatAddNothing(t *testing.T) {
+	first := &APIResponse{Api: "BrasilAPI", Data: &CEP{Cep: "01310100", Uf: "SP"}}
+	second := &APIResponse{Api: "ViaCEP", Data: &CEP{Cep: "01310-100", Uf: "SP"}}
+
+	merged := MergeCEPs([]*APIResponse{first, second})
+
+	assert.Equal(t, "BrasilAPI", merged.Api)
+	assert.Equal(t, map[string]string{"cep": "BrasilAPI", "uf": "BrasilAPI"}, merged.Sources)
+}
+
+func TestNewMergedAddressTranslatesProvenance(t *testing.T) {
+	merged := MergeCEPs([]*APIResponse{
+		{Api: "BrasilAPI", Data: &CEP{Cep: "01310100", Estado: "SP", Cidade: "São Paulo", Rua: "Avenida Paulista"}},
+		{Api: "ViaCEP", Data: &CEP{Cep: "01310-100", Logradouro: "Avenida Paulista", Bairro: "Bela Vista", Uf: "SP", Ibge: "3550308"}},
+	})
+
+	address := NewMergedAddress(merged)
+
+	assert.Equal(t, "01310-100", address.Cep)
+	assert.Equal(t, "BrasilAPI,ViaCEP", address.Source)
+	assert.Equal(t, "Sudeste", address.Region)
+	assert.Equal(t, map[string]string{
+		"cep":          "BrasilAPI",
+		"street":       "BrasilAPI",
+		"neighborhood": "ViaCEP",
+		"city":         "BrasilAPI",
+		"state_code":   "BrasilAPI",
+		"ibge":         "ViaCEP",
+	}, address.Sources)
+}
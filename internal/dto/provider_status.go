@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+// ProviderStatus summarizes a provider's most recent calls.
+type ProviderStatus struct {
+	Name         string     `json:"name"`
+	Requests     int        `json:"requests"`
+	SuccessRate  float64    `json:"success_rate"`
+	AvgLatencyMs float64    `json:"avg_latency_ms"`
+	P95LatencyMs float64    `json:"p95_latency_ms"`
+	LastSuccess  *time.Time `json:"last_success,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	Circuit      string     `json:"circuit,omitempty"`
+}
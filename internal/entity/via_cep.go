@@ -14,4 +14,15 @@ type ViaCEP struct {
 	Gia         string `json:"gia"`
 	Ddd         string `json:"ddd"`
 	Siafi       string `json:"siafi"`
+	Erro        Flag   `json:"erro"`
+}
+
+// Flag decodes ViaCEP's "erro" marker, which has been sent both as a JSON
+// boolean and as the string "true".
+type Flag bool
+
+func (f *Flag) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	*f = Flag(value == "true" || value == `"true"`)
+	return nil
 }
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+	BackendNone   = "none"
+)
+
+// Entry is a cached lookup result. NotFound entries record that every
+// provider reported the CEP as nonexistent (negative caching).
+type Entry struct {
+	CEP      *dto.CEP `json:"cep,omitempty"`
+	Api      string   `json:"api,omitempty"`
+	NotFound bool     `json:"not_found,omitempty"`
+}
+
+// Cache stores lookup results keyed by the normalized 8-digit CEP. Entries
+// must be treated as immutable by callers.
+type Cache interface {
+	Get(ctx context.Context, cep string) (*Entry, bool)
+	Set(ctx context.Context, cep string, entry *Entry)
+}
+
+// New builds the cache backend selected by config.CacheBackend, or a no-op
+// cache when caching is disabled. It rejects an unknown backend, so a typo
+// fails at startup instead of leaving each replica on a private cache.
+func New(config *configs.Config) (Cache, error) {
+	switch config.CacheBackend {
+	case "", BackendMemory, BackendRedis, BackendNone:
+	default:
+		return nil, fmt.Errorf("CACHE_BACKEND inválido: %q (use %s, %s ou %s)", config.CacheBackend, BackendMemory, BackendRedis, BackendNone)
+	}
+
+	if config.CacheBackend == BackendNone || config.CacheTTL <= 0 {
+		return NewNoop(), nil
+	}
+
+	if config.CacheBackend == BackendRedis {
+		client := redis.NewClient(&redis.Options{
+			Addr:         config.RedisAddr,
+			Password:     config.RedisPassword,
+			DB:           config.RedisDB,
+			DialTimeout:  config.RedisTimeout,
+			ReadTimeout:  config.RedisTimeout,
+			WriteTimeout: config.RedisTimeout,
+		})
+		return NewRedis(client, config.CacheTTL, config.CacheNegativeTTL), nil
+	}
+
+	if config.CacheSize <= 0 {
+		return NewNoop(), nil
+	}
+	return NewMemory(config.CacheSize, config.CacheTTL, config.CacheNegativeTTL), nil
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReturnsMemoryCache(t *testing.T) {
+	config := &configs.Config{
+		CacheSize:        100,
+		CacheTTL:         time.Hour,
+		CacheNegativeTTL: time.Minute,
+	}
+
+	cache, err := New(config)
+	assert.NoError(t, err)
+	assert.IsType(t, &Memory{}, cache)
+}
+
+func TestNewReturnsRedisCache(t *testing.T) {
+	config := &configs.Config{
+		CacheBackend: BackendRedis,
+		CacheTTL:     time.Hour,
+		RedisAddr:    "localhost:6379",
+	}
+
+	cache, err := New(config)
+	assert.NoError(t, err)
+	assert.IsType(t, &Redis{}, cache)
+}
+
+func TestNewReturnsNoopWhenDisabled(t *testing.T) {
+	for _, config := range []*configs.Config{
+		{CacheSize: 0, CacheTTL: time.Hour},
+		{CacheSize: 100, CacheTTL: 0},
+		{CacheBackend: BackendNone, CacheSize: 100, CacheTTL: time.Hour},
+	} {
+		cache, err := New(config)
+		assert.NoError(t, err)
+		assert.IsType(t, &Noop{}, cache)
+	}
+}
+
+func TestNewRejectsUnknownBackend(t *testing.T) {
+	_, err := New(&configs.Config{CacheBackend: "redsi", CacheSize: 100, CacheTTL: time.Hour})
+	assert.ErrorContains(t, err, "CACHE_BACKEND")
+
+	_, err = New(&configs.Config{CacheBackend: "redsi", CacheTTL: 0})
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a bounded, in-process LRU cache with per-entry expiration.
+type Memory struct {
+	mu          sync.Mutex
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	items       map[string]*list.Element
+	order       *list.List
+	now         func() time.Time
+}
+
+type memoryItem struct {
+	key       string
+	entry     *Entry
+	expiresAt time.Time
+}
+
+func NewMemory(size int, ttl, negativeTTL time.Duration) *Memory {
+	return &Memory{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		items:       make(map[string]*list.Element, size),
+		order:       list.New(),
+		now:         time.Now,
+	}
+}
+
+func (m *Memory) Get(ctx context.Context, cep string) (*Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[cep]
+	if !ok {
+		return nil, false
+	}
+
+	item := elem.Value.(*memoryItem)
+	if !m.now().Before(item.expiresAt) {
+		m.remove(elem)
+		return nil, false
+	}
+
+	m.order.MoveToFront(elem)
+	return item.entry, true
+}
+
+func (m *Memory) Set(ctx context.Context, cep string, entry *Entry) {
+	ttl := m.ttl
+	if entry.NotFound {
+		ttl = m.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := m.now().Add(ttl)
+	if elem, ok := m.items[cep]; ok {
+		item := elem.Value.(*memoryItem)
+		item.entry = entry
+		item.expiresAt = expiresAt
+		m.order.MoveToFront(elem)
+		return
+	}
+
+	m.items[cep] = m.order.PushFront(&memoryItem{key: cep, entry: entry, expiresAt: expiresAt})
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *Memory) remove(elem *list.Element) {
+	m.order.Remove(elem)
+	delete(m.items, elem.Value.(*memoryItem).key)
+}
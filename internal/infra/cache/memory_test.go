@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryGetMiss(t *testing.T) {
+	cache := NewMemory(10, time.Minute, time.Minute)
+
+	entry, ok := cache.Get(context.Background(), "01310100")
+
+	assert.False(t, ok)
+	assert.Nil(t, entry)
+}
+
+func TestMemorySetAndGet(t *testing.T) {
+	cache := NewMemory(10, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	cache.Set(ctx, "01310100", &Entry{CEP: &dto.CEP{Cep: "01310-100"}, Api: "BrasilAPI"})
+
+	entry, ok := cache.Get(ctx, "01310100")
+	assert.True(t, ok)
+	assert.Equal(t, "01310-100", entry.CEP.Cep)
+	assert.Equal(t, "BrasilAPI", entry.Api)
+}
+
+func TestMemoryExpiresEntries(t *testing.T) {
+	cache := NewMemory(10, time.Minute, time.Second)
+	ctx := context.Background()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Set(ctx, "01310100", &Entry{CEP: &dto.CEP{Cep: "01310-100"}})
+	cache.Set(ctx, "99999999", &Entry{NotFound: true})
+
+	now = now.Add(2 * time.Second)
+	_, ok := cache.Get(ctx, "99999999")
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "01310100")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.Get(ctx, "01310100")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemory(2, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	cache.Set(ctx, "01310100", &Entry{Api: "a"})
+	cache.Set(ctx, "01153000", &Entry{Api: "b"})
+	cache.Get(ctx, "01310100")
+	cache.Set(ctx, "20040002", &Entry{Api: "c"})
+
+	_, ok := cache.Get(ctx, "01153000")
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "01310100")
+	assert.True(t, ok)
+	_, ok = cache.Get(ctx, "20040002")
+	assert.True(t, ok)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestMemorySkipsNegativeEntriesWithoutTTL(t *testing.T) {
+	cache := NewMemory(10, time.Minute, 0)
+	ctx := context.Background()
+
+	cache.Set(ctx, "99999999", &Entry{NotFound: true})
+
+	_, ok := cache.Get(ctx, "99999999")
+	assert.False(t, ok)
+}
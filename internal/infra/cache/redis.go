@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "cep:"
+
+// redisRetryInterval is how long the cache stays bypassed after a Redis
+// error before it is tried again.
+const redisRetryInterval = 5 * time.Second
+
+// Redis is a cache shared by every replica. Any Redis failure degrades to a
+// cache miss so lookups keep working when Redis is down. A call abandoned
+// because the caller's context ended is a miss too, but says nothing about
+// Redis, so it does not bypass the cache for the other requests.
+type Redis struct {
+	client      redis.UniversalClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	downUntil   atomic.Int64
+	now         func() time.Time
+}
+
+func NewRedis(client redis.UniversalClient, ttl, negativeTTL time.Duration) *Redis {
+	return &Redis{
+		client:      client,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, cep string) (*Entry, bool) {
+	if r.isDown() {
+		return nil, false
+	}
+
+	payload, err := r.client.Get(ctx, redisKeyPrefix+cep).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	}
+	if err != nil {
+		r.markDown(ctx, err)
+		return nil, false
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		slog.WarnContext(ctx, "entrada do cache inválida", "backend", BackendRedis, "error", err)
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (r *Redis) Set(ctx context.Context, cep string, entry *Entry) {
+	ttl := r.ttl
+	if entry.NotFound {
+		ttl = r.negativeTTL
+	}
+	if ttl <= 0 || r.isDown() {
+		return
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		slog.WarnContext(ctx, "erro ao codificar entrada do cache", "backend", BackendRedis, "error", err)
+		return
+	}
+
+	if err := r.client.Set(ctx, redisKeyPrefix+cep, payload, ttl).Err(); err != nil {
+		r.markDown(ctx, err)
+	}
+}
+
+func (r *Redis) isDown() bool {
+	return r.now().UnixNano() < r.downUntil.Load()
+}
+
+func (r *Redis) markDown(ctx context.Context, err error) {
+	// Dial and read timeouts also match context.DeadlineExceeded, so only
+	// the caller's own context tells a cancelled call from a slow Redis.
+	if ctx.Err() != nil {
+		return
+	}
+	slog.WarnContext(ctx, "redis indisponível, cache desativado temporariamente", "retry_in", redisRetryInterval, "error", err)
+	r.downUntil.Store(r.now().Add(redisRetryInterval).UnixNano())
+}
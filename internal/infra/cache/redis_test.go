@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRedis(t *testing.T) (*miniredis.Miniredis, *Redis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { client.Close() })
+	return server, NewRedis(client, time.Hour, time.Minute)
+}
+
+func TestRedisSetAndGet(t *testing.T) {
+	_, cache := setupRedis(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "01310100", &Entry{CEP: &dto.CEP{Cep: "01310-100", Rua: "Avenida Paulista"}, Api: "BrasilAPI"})
+
+	entry, ok := cache.Get(ctx, "01310100")
+	assert.True(t, ok)
+	assert.Equal(t, "01310-100", entry.CEP.Cep)
+	assert.Equal(t, "Avenida Paulista", entry.CEP.Rua)
+	assert.Equal(t, "BrasilAPI", entry.Api)
+}
+
+func TestRedisGetMiss(t *testing.T) {
+	_, cache := setupRedis(t)
+
+	entry, ok := cache.Get(context.Background(), "01310100")
+
+	assert.False(t, ok)
+	assert.Nil(t, entry)
+}
+
+func TestRedisUsesTTLPerEntryKind(t *testing.T) {
+	server, cache := setupRedis(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "01310100", &Entry{CEP: &dto.CEP{Cep: "01310-100"}})
+	cache.Set(ctx, "99999999", &Entry{NotFound: true})
+
+	assert.Equal(t, time.Hour, server.TTL(redisKeyPrefix+"01310100"))
+	assert.Equal(t, time.Minute, server.TTL(redisKeyPrefix+"99999999"))
+
+	server.FastForward(2 * time.Minute)
+
+	entry, ok := cache.Get(ctx, "01310100")
+	assert.True(t, ok)
+	assert.False(t, entry.NotFound)
+	_, ok = cache.Get(ctx, "99999999")
+	assert.False(t, ok)
+}
+
+func TestRedisIgnoresCorruptedEntries(t *testing.T) {
+	server, cache := setupRedis(t)
+	server.Set(redisKeyPrefix+"01310100", "not json")
+
+	_, ok := cache.Get(context.Background(), "01310100")
+
+	assert.False(t, ok)
+}
+
+func TestRedisFallsBackToMissWhenDown(t *testing.T) {
+	server, cache := setupRedis(t)
+	ctx := context.Background()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Set(ctx, "01310100", &Entry{CEP: &dto.CEP{Cep: "01310-100"}})
+	server.Close()
+
+	_, ok := cache.Get(ctx, "01310100")
+	assert.False(t, ok)
+	assert.True(t, cache.isDown())
+
+	assert.NotPanics(t, func() {
+		cache.Set(ctx, "01153000", &Entry{CEP: &dto.CEP{Cep: "01153-000"}})
+	})
+
+	now = now.Add(redisRetryInterval)
+	assert.False(t, cache.isDown())
+}
+
+func TestRedisStaysUpWhenCallerContextEnds(t *testing.T) {
+	server, cache := setupRedis(t)
+	cache.Set(context.Background(), "01310100", &Entry{CEP: &dto.CEP{Cep: "01310-100"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, ok := cache.Get(ctx, "01310100")
+	assert.False(t, ok)
+	cache.Set(ctx, "01153000", &Entry{CEP: &dto.CEP{Cep: "01153-000"}})
+	assert.False(t, cache.isDown())
+
+	entry, ok := cache.Get(context.Background(), "01310100")
+	assert.True(t, ok)
+	assert.Equal(t, "01310-100", entry.CEP.Cep)
+	assert.False(t, server.Exists(redisKeyPrefix+"01153000"))
+}
@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/entity"
+)
+
+const BrasilAPIName = "BrasilAPI"
+
+type BrasilAPIProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewBrasilAPIProvider(url string, client *http.Client) *BrasilAPIProvider {
+	return &BrasilAPIProvider{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *BrasilAPIProvider) Name() string {
+	return BrasilAPIName
+}
+
+func (p *BrasilAPIProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	var apiResp entity.BrasilAPICEP
+	if err := getJSON(ctx, p.client, BrasilAPIName, fmt.Sprintf(p.url, cep), &apiResp); err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, ErrCEPNotFound
+		}
+		return nil, err
+	}
+
+	return &dto.CEP{
+		Cep:     apiResp.Cep,
+		Estado:  apiResp.State,
+		Cidade:  apiResp.City,
+		Bairro:  apiResp.Neighborhood,
+		Rua:     apiResp.Street,
+		Servico: apiResp.Service,
+	}, nil
+}
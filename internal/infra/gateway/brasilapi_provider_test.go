@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrasilAPIProviderLookupSuccess(t *testing.T) {
+	mockResponse := entity.BrasilAPICEP{
+		Cep:          "01310-100",
+		State:        "SP",
+		City:         "São Paulo",
+		Neighborhood: "Bela Vista",
+		Street:       "Avenida Paulista",
+		Service:      "correios",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(mockResponse)
+	}))
+	defer server.Close()
+
+	provider := NewBrasilAPIProvider(server.URL+"/%s", server.Client())
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, "01310-100", result.Cep)
+	assert.Equal(t, "SP", result.Estado)
+	assert.Equal(t, "São Paulo", result.Cidade)
+	assert.Equal(t, "Bela Vista", result.Bairro)
+	assert.Equal(t, "Avenida Paulista", result.Rua)
+	assert.Equal(t, "correios", result.Servico)
+}
+
+func TestBrasilAPIProviderLookupHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	provider := NewBrasilAPIProvider(server.URL+"/%s", server.Client())
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "API retornou status 500")
+	assert.NotErrorIs(t, err, ErrCEPNotFound)
+}
+
+func TestBrasilAPIProviderLookupNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	provider := NewBrasilAPIProvider(server.URL+"/%s", server.Client())
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "99999999")
+
+	assert.ErrorIs(t, err, ErrCEPNotFound)
+	assert.Nil(t, result)
+}
+
+func TestBrasilAPIProviderLookupInvalidJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("invalid json"))
+	}))
+	defer server.Close()
+
+	provider := NewBrasilAPIProvider(server.URL+"/%s", server.Client())
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestBrasilAPIProviderLookupContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := NewBrasilAPIProvider(server.URL+"/%s", server.Client())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestBrasilAPIProviderLookupInvalidURL(t *testing.T) {
+	provider := NewBrasilAPIProvider("://invalid-url", http.DefaultClient)
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
@@ -3,120 +3,176 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/AmandaIsrael/faster-cep-api/configs"
 	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
-	"github.com/AmandaIsrael/faster-cep-api/internal/entity"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
-type ICEPGateway interface {
-	GetBrasilAPICEP(ctx context.Context, cep string) (*dto.CEP, error)
-	GetViaCEP(ctx context.Context, cep string) (*dto.CEP, error)
+// StatusError reports an upstream answer with a status other than 200.
+type StatusError struct {
+	StatusCode int
 }
 
-type CEPGateway struct {
-	config *configs.Config
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API retornou status %d", e.StatusCode)
 }
 
-func NewCEPGateway(config *configs.Config) *CEPGateway {
-	return &CEPGateway{
-		config: config,
-	}
+// DecodeError reports an upstream body that is not the expected JSON.
+type DecodeError struct {
+	Err error
 }
 
-func (c *CEPGateway) GetBrasilAPICEP(ctx context.Context, cep string) (*dto.CEP, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(c.config.BrasilAPIURL, cep), nil)
-	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao criar requisição BrasilAPI: %v\n", err)
-		return nil, err
-	}
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao executar requisição BrasilAPI: %v\n", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("[CEPGATEWAY] BrasilAPI retornou status %d\n", resp.StatusCode)
-		return nil, fmt.Errorf("API retornou status %d", resp.StatusCode)
+const (
+	ErrorTypeTimeout     = "timeout"
+	ErrorTypeStatus      = "status"
+	ErrorTypeDecode      = "decode"
+	ErrorTypeNotFound    = "not_found"
+	ErrorTypeTransport   = "transport"
+	ErrorTypeCircuitOpen = "circuit_open"
+	ErrorTypeRateLimited = "rate_limited"
+)
+
+// ErrorType classifies a provider error for metrics and logs.
+func ErrorType(err error) string {
+	var statusErr *StatusError
+	var decodeErr *DecodeError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrCEPNotFound):
+		return ErrorTypeNotFound
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorTypeCircuitOpen
+	case errors.Is(err, ErrRateLimited):
+		return ErrorTypeRateLimited
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTypeTimeout
+	case errors.As(err, &statusErr):
+		return ErrorTypeStatus
+	case errors.As(err, &decodeErr):
+		return ErrorTypeDecode
+	default:
+		return ErrorTypeTransport
 	}
+}
 
-	result, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao ler resposta BrasilAPI: %v\n", err)
-		return nil, err
+type ICEPGateway interface {
+	Providers() []Provider
+	Available() ([]Provider, []*UnavailableError)
+	Status() []dto.ProviderStatus
+	LatencyP95(name string) (time.Duration, bool)
+}
+
+type CEPGateway struct {
+	*Registry
+}
+
+// builtins are the providers NewCEPGateway registers, in registration
+// order, each built around its own HTTP client.
+var builtins = []struct {
+	name string
+	new  func(config *configs.Config, client *http.Client) Provider
+}{
+	{BrasilAPIName, func(config *configs.Config, client *http.Client) Provider {
+		return NewBrasilAPIProvider(config.BrasilAPIURL, client)
+	}},
+	{ViaCEPName, func(config *configs.Config, client *http.Client) Provider {
+		return NewViaCEPProvider(config.ViaCEPURL, client)
+	}},
+}
+
+// ProviderNames lists the providers NewCEPGateway registers, in
+// registration order, for configs.Load to read their settings.
+func ProviderNames() []string {
+	names := make([]string, len(builtins))
+	for i, builtin := range builtins {
+		names[i] = builtin.name
 	}
+	return names
+}
 
-	var apiResp entity.BrasilAPICEP
-	err = json.Unmarshal(result, &apiResp)
-	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao decodificar JSON BrasilAPI: %v\n", err)
-		return nil, err
+// NewCEPGateway registers BrasilAPI and ViaCEP, each with its own HTTP
+// client and resilience settings taken from config.Providers under its
+// name. It fails when a provider's HTTP client settings are invalid.
+func NewCEPGateway(config *configs.Config) (*CEPGateway, error) {
+	registry := NewRegistry()
+	for _, builtin := range builtins {
+		provider := config.Providers[builtin.name]
+		client, err := NewHTTPClient(provider.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", builtin.name, err)
+		}
+		registry.Register(resilient(builtin.new(config, client), providerSettings{
+			timeout:       provider.Timeout,
+			rateLimit:     provider.RateLimit,
+			burst:         provider.Burst,
+			maxConcurrent: provider.MaxConcurrent,
+		}, config))
 	}
 
-	log.Println("[CEPGATEWAY] Dados obtidos da BrasilAPI com sucesso")
-	return &dto.CEP{
-		Cep:     apiResp.Cep,
-		Estado:  apiResp.State,
-		Cidade:  apiResp.City,
-		Bairro:  apiResp.Neighborhood,
-		Rua:     apiResp.Street,
-		Servico: apiResp.Service,
-	}, nil
+	return &CEPGateway{Registry: registry}, nil
+}
+
+// providerSettings are the knobs resilient takes per provider.
+type providerSettings struct {
+	timeout       time.Duration
+	rateLimit     float64
+	burst         int
+	maxConcurrent int
 }
 
-func (c *CEPGateway) GetViaCEP(ctx context.Context, cep string) (*dto.CEP, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(c.config.ViaCEPURL, cep), nil)
+// resilient wraps p, from the outside in, in an outbound limit, a circuit
+// breaker, a timeout covering all attempts, and retries.
+func resilient(p Provider, settings providerSettings, config *configs.Config) Provider {
+	p = WithRetry(p, config.ProviderRetries, config.ProviderRetryBackoff)
+	p = WithTimeout(p, settings.timeout)
+	p = WithBreaker(p, config.BreakerThreshold, config.BreakerCooldown)
+	return WithLimit(p, settings.rateLimit, settings.burst, settings.maxConcurrent)
+}
+
+// getJSON fetches url into out. Failures are returned, not logged: the
+// tracked provider wrapping every Lookup logs the outcome once.
+func getJSON(ctx context.Context, client *http.Client, provider, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao criar requisição ViaCEP: %v\n", err)
-		return nil, err
+		return err
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao executar requisição ViaCEP: %v\n", err)
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
+	slog.DebugContext(ctx, "resposta do provedor", "provider", provider, "url", url, "status", resp.StatusCode)
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("[CEPGATEWAY] ViaCEP retornou status %d\n", resp.StatusCode)
-		return nil, fmt.Errorf("API retornou status %d", resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	result, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao ler resposta ViaCEP: %v\n", err)
-		return nil, err
+		return err
 	}
 
-	var apiResp entity.ViaCEP
-	err = json.Unmarshal(result, &apiResp)
-	if err != nil {
-		log.Printf("[CEPGATEWAY] Erro ao decodificar JSON ViaCEP: %v\n", err)
-		return nil, err
+	if err := json.Unmarshal(result, out); err != nil {
+		return &DecodeError{Err: err}
 	}
-
-	log.Println("[CEPGATEWAY] Dados obtidos da ViaCEP com sucesso")
-	return &dto.CEP{
-		Cep:         apiResp.Cep,
-		Logradouro:  apiResp.Logradouro,
-		Complemento: apiResp.Complemento,
-		Unidade:     apiResp.Unidade,
-		Bairro:      apiResp.Bairro,
-		Localidade:  apiResp.Localidade,
-		Uf:          apiResp.Uf,
-		Estado:      apiResp.Uf,
-		Regiao:      apiResp.Regiao,
-		Ibge:        apiResp.Ibge,
-		Gia:         apiResp.Gia,
-		Ddd:         apiResp.Ddd,
-		Siafi:       apiResp.Siafi,
-	}, nil
+	return nil
 }
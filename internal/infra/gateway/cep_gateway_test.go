@@ -2,17 +2,32 @@ package gateway
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/AmandaIsrael/faster-cep-api/configs"
-	"github.com/AmandaIsrael/faster-cep-api/internal/entity"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
 	"github.com/stretchr/testify/assert"
 )
 
+type stubProvider struct {
+	name string
+}
+
+func (s *stubProvider) Name() string {
+	return s.name
+}
+
+func (s *stubProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	return &dto.CEP{Cep: cep}, nil
+}
+
 func TestNewCEPGateway(t *testing.T) {
 	config := &configs.Config{
 		BrasilAPIURL: "https://brasilapi.com.br/api/cep/v1/%s",
@@ -20,236 +35,108 @@ func TestNewCEPGateway(t *testing.T) {
 		Timeout:      time.Second,
 	}
 
-	gateway := NewCEPGateway(config)
+	gateway, err := NewCEPGateway(config)
+	assert.NoError(t, err)
 
 	assert.NotNil(t, gateway)
-	assert.Equal(t, config, gateway.config)
 }
 
-func TestCEPGatewayGetBrasilAPICEPSuccess(t *testing.T) {
-	mockResponse := entity.BrasilAPICEP{
-		Cep:          "01310-100",
-		State:        "SP",
-		City:         "São Paulo",
-		Neighborhood: "Bela Vista",
-		Street:       "Avenida Paulista",
-		Service:      "correios",
-	}
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(mockResponse)
-	}))
-	defer server.Close()
-
+func TestNewCEPGatewayRegistersDefaultProviders(t *testing.T) {
 	config := &configs.Config{
-		BrasilAPIURL: server.URL + "/%s",
+		BrasilAPIURL: "https://brasilapi.com.br/api/cep/v1/%s",
+		ViaCEPURL:    "http://viacep.com.br/ws/%s/json/",
 	}
-	gateway := NewCEPGateway(config)
-
-	ctx := context.Background()
-	result, err := gateway.GetBrasilAPICEP(ctx, "01310100")
 
+	gateway, err := NewCEPGateway(config)
 	assert.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.Equal(t, "01310-100", result.Cep)
-	assert.Equal(t, "SP", result.Estado)
-	assert.Equal(t, "São Paulo", result.Cidade)
-	assert.Equal(t, "Bela Vista", result.Bairro)
-	assert.Equal(t, "Avenida Paulista", result.Rua)
-	assert.Equal(t, "correios", result.Servico)
+
+	providers := gateway.Providers()
+	assert.Len(t, providers, 2)
+	assert.Equal(t, BrasilAPIName, providers[0].Name())
+	assert.Equal(t, ViaCEPName, providers[1].Name())
 }
 
-func TestCEPGatewayGetBrasilAPICEPHTTPError(t *testing.T) {
+func TestNewCEPGatewayAppliesProviderConfigByName(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 	}))
 	defer server.Close()
 
-	config := &configs.Config{
+	gateway, err := NewCEPGateway(&configs.Config{
 		BrasilAPIURL: server.URL + "/%s",
-	}
-	gateway := NewCEPGateway(config)
-
-	ctx := context.Background()
-	result, err := gateway.GetBrasilAPICEP(ctx, "00000000")
-
-	assert.Error(t, err)
-	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "API retornou status 404")
-}
-
-func TestCEPGatewayGetBrasilAPICEPInvalidJSON(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("invalid json"))
-	}))
-	defer server.Close()
+		ViaCEPURL:    server.URL + "/%s",
+		Providers: map[string]configs.ProviderConfig{
+			ViaCEPName: {RateLimit: 0.001, Burst: 1},
+		},
+	})
+	assert.NoError(t, err)
 
-	config := &configs.Config{
-		BrasilAPIURL: server.URL + "/%s",
+	for _, p := range gateway.Providers() {
+		p.Lookup(context.Background(), "01310100")
 	}
-	gateway := NewCEPGateway(config)
 
-	ctx := context.Background()
-	result, err := gateway.GetBrasilAPICEP(ctx, "01310100")
-
-	assert.Error(t, err)
-	assert.Nil(t, result)
-}
-
-func TestCEPGatewayGetBrasilAPICEPContextCancellation(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(100 * time.Millisecond)
-		w.WriteHeader(http.StatusOK)
-	}))
-	defer server.Close()
-
-	config := &configs.Config{
-		BrasilAPIURL: server.URL + "/%s",
+	providers, skipped := gateway.Available()
+	assert.Len(t, providers, 1)
+	assert.Equal(t, BrasilAPIName, providers[0].Name())
+	if assert.Len(t, skipped, 1) {
+		assert.Equal(t, ViaCEPName, skipped[0].Provider)
+		assert.ErrorIs(t, skipped[0], ErrRateLimited)
 	}
-	gateway := NewCEPGateway(config)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
-	defer cancel()
-
-	result, err := gateway.GetBrasilAPICEP(ctx, "01310100")
-
-	assert.Error(t, err)
-	assert.Nil(t, result)
 }
 
-func TestCEPGatewayGetViaCEPSuccess(t *testing.T) {
-	mockResponse := entity.ViaCEP{
-		Cep:         "01310-100",
-		Logradouro:  "Avenida Paulista",
-		Complemento: "",
-		Unidade:     "",
-		Bairro:      "Bela Vista",
-		Localidade:  "São Paulo",
-		Uf:          "SP",
-		Estado:      "SP",
-		Regiao:      "Sudeste",
-		Ibge:        "3550308",
-		Gia:         "1004",
-		Ddd:         "11",
-		Siafi:       "7107",
-	}
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(mockResponse)
-	}))
-	defer server.Close()
-
-	config := &configs.Config{
-		ViaCEPURL: server.URL + "/%s",
-	}
-	gateway := NewCEPGateway(config)
+func TestCEPGatewayRegisterAdditionalProvider(t *testing.T) {
+	config := &configs.Config{}
+	gateway, err := NewCEPGateway(config)
+	assert.NoError(t, err)
 
-	ctx := context.Background()
-	result, err := gateway.GetViaCEP(ctx, "01310100")
+	gateway.Register(&stubProvider{name: "OpenCEP"})
 
-	assert.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.Equal(t, "01310-100", result.Cep)
-	assert.Equal(t, "Avenida Paulista", result.Logradouro)
-	assert.Equal(t, "Bela Vista", result.Bairro)
-	assert.Equal(t, "São Paulo", result.Localidade)
-	assert.Equal(t, "SP", result.Uf)
-	assert.Equal(t, "SP", result.Estado)
-	assert.Equal(t, "Sudeste", result.Regiao)
-	assert.Equal(t, "3550308", result.Ibge)
-	assert.Equal(t, "1004", result.Gia)
-	assert.Equal(t, "11", result.Ddd)
-	assert.Equal(t, "7107", result.Siafi)
+	providers := gateway.Providers()
+	assert.Len(t, providers, 3)
+	assert.Equal(t, "OpenCEP", providers[2].Name())
 }
 
-func TestCEPGatewayGetViaCEPHTTPError(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-	}))
-	defer server.Close()
-
-	config := &configs.Config{
-		ViaCEPURL: server.URL + "/%s",
-	}
-	gateway := NewCEPGateway(config)
+func TestRegistryProvidersReturnsCopy(t *testing.T) {
+	registry := NewRegistry(&stubProvider{name: "A"}, &stubProvider{name: "B"})
 
-	ctx := context.Background()
-	result, err := gateway.GetViaCEP(ctx, "00000000")
+	providers := registry.Providers()
+	providers[0] = &stubProvider{name: "C"}
 
-	assert.Error(t, err)
-	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "API retornou status 500")
+	assert.Equal(t, "A", registry.Providers()[0].Name())
 }
 
-func TestCEPGatewayGetViaCEPInvalidJSON(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("{ invalid json }"))
-	}))
-	defer server.Close()
-
-	config := &configs.Config{
-		ViaCEPURL: server.URL + "/%s",
+func TestErrorType(t *testing.T) {
+	errorTypes := map[error]string{
+		ErrCEPNotFound:                                      ErrorTypeNotFound,
+		context.DeadlineExceeded:                            ErrorTypeTimeout,
+		&StatusError{StatusCode: 500}:                       ErrorTypeStatus,
+		&DecodeError{Err: io.ErrUnexpectedEOF}:              ErrorTypeDecode,
+		errors.New("connection refused"):                    ErrorTypeTransport,
+		fmt.Errorf("wrapped: %w", context.DeadlineExceeded): ErrorTypeTimeout,
 	}
-	gateway := NewCEPGateway(config)
 
-	ctx := context.Background()
-	result, err := gateway.GetViaCEP(ctx, "01310100")
-
-	assert.Error(t, err)
-	assert.Nil(t, result)
+	for err, expected := range errorTypes {
+		assert.Equal(t, expected, ErrorType(err), err.Error())
+	}
 }
 
-func TestCEPGatewayGetViaCEPContextCancellation(t *testing.T) {
+func TestGetJSONPropagatesTraceContext(t *testing.T) {
+	_, restore := tracing.InstallInMemory()
+	defer restore()
+
+	var traceparent string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(100 * time.Millisecond)
-		w.WriteHeader(http.StatusOK)
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte("{}"))
 	}))
 	defer server.Close()
 
-	config := &configs.Config{
-		ViaCEPURL: server.URL + "/%s",
-	}
-	gateway := NewCEPGateway(config)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
-	defer cancel()
-
-	result, err := gateway.GetViaCEP(ctx, "01310100")
-
-	assert.Error(t, err)
-	assert.Nil(t, result)
-}
-
-func TestCEPGatewayGetBrasilAPICEPInvalidURL(t *testing.T) {
-	config := &configs.Config{
-		BrasilAPIURL: "://invalid-url",
-	}
-	gateway := NewCEPGateway(config)
-
-	ctx := context.Background()
-	result, err := gateway.GetBrasilAPICEP(ctx, "01310100")
-
-	assert.Error(t, err)
-	assert.Nil(t, result)
-}
+	ctx, span := tracing.Tracer().Start(context.Background(), "parent")
+	defer span.End()
+	var out map[string]any
+	err := getJSON(ctx, http.DefaultClient, "Test", server.URL, &out)
 
-func TestCEPGatewayGetViaCEPInvalidURL(t *testing.T) {
-	config := &configs.Config{
-		ViaCEPURL: "://invalid-url",
-	}
-	gateway := NewCEPGateway(config)
-
-	ctx := context.Background()
-	result, err := gateway.GetViaCEP(ctx, "01310100")
-
-	assert.Error(t, err)
-	assert.Nil(t, result)
+	assert.NoError(t, err)
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+	assert.Contains(t, traceparent, span.SpanContext().SpanID().String())
 }
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+)
+
+// NewHTTPClient builds a client with its own connection pool, tuned by
+// settings. Each provider gets one so that a slow or misbehaving upstream
+// cannot starve the other of idle connections.
+//
+// The client has no overall timeout: the race and per-provider contexts
+// bound every request.
+func NewHTTPClient(settings configs.HTTPClientConfig) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if settings.ProxyURL != "" {
+		proxyURL, err := url.Parse(settings.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("HTTP_PROXY_URL inválida: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(settings.CABundle)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   settings.DialTimeout,
+		KeepAlive: settings.KeepAlive,
+	}
+	transport := &http.Transport{
+		Proxy:               proxy,
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: settings.TLSHandshakeTimeout,
+		MaxIdleConns:        settings.MaxIdleConns,
+		MaxIdleConnsPerHost: settings.MaxIdleConnsPerHost,
+		IdleConnTimeout:     settings.IdleConnTimeout,
+		ForceAttemptHTTP2:   settings.HTTP2,
+	}
+	if !settings.HTTP2 {
+		// A non-nil, empty map is how net/http is told not to negotiate h2.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newTLSConfig trusts the system roots plus the PEM certificates in
+// caBundle, for egress proxies that re-sign upstream traffic.
+func newTLSConfig(caBundle string) (*tls.Config, error) {
+	if caBundle == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler HTTP_CA_BUNDLE: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("HTTP_CA_BUNDLE %s não contém certificados PEM válidos", caBundle)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPClientAppliesSettings(t *testing.T) {
+	settings := configs.HTTPClientConfig{
+		MaxIdleConns:        20,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     45 * time.Second,
+		TLSHandshakeTimeout: 3 * time.Second,
+		HTTP2:               true,
+		ProxyURL:            "http://proxy.internal:3128",
+	}
+
+	client, err := NewHTTPClient(settings)
+	assert.NoError(t, err)
+
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(t, 20, transport.MaxIdleConns)
+	assert.Equal(t, 10, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 45*time.Second, transport.IdleConnTimeout)
+	assert.Equal(t, 3*time.Second, transport.TLSHandshakeTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.TLSNextProto)
+	assert.Zero(t, client.Timeout)
+
+	proxyURL, err := transport.Proxy(httptest.NewRequest("GET", "https://brasilapi.com.br/api/cep/v1/01001000", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.internal:3128", proxyURL.String())
+
+	other, err := NewHTTPClient(settings)
+	assert.NoError(t, err)
+	assert.NotSame(t, transport, other.Transport, "each client must have its own pool")
+}
+
+func TestNewHTTPClientWithoutHTTP2(t *testing.T) {
+	client, err := NewHTTPClient(configs.HTTPClientConfig{HTTP2: false})
+	assert.NoError(t, err)
+
+	transport := client.Transport.(*http.Transport)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+}
+
+func TestNewHTTPClientInvalidProxy(t *testing.T) {
+	_, err := NewHTTPClient(configs.HTTPClientConfig{ProxyURL: "://proxy"})
+	assert.ErrorContains(t, err, "HTTP_PROXY_URL")
+
+	_, err = NewCEPGateway(&configs.Config{Providers: map[string]configs.ProviderConfig{
+		ViaCEPName: {HTTP: configs.HTTPClientConfig{ProxyURL: "://proxy"}},
+	}})
+	assert.ErrorContains(t, err, ViaCEPName)
+}
+
+func TestNewHTTPClientTrustsCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(bundle, certPEM, 0o600))
+
+	client, err := NewHTTPClient(configs.HTTPClientConfig{CABundle: bundle})
+	assert.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+
+	untrusting, err := NewHTTPClient(configs.HTTPClientConfig{})
+	assert.NoError(t, err)
+	_, err = untrusting.Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestNewHTTPClientInvalidCABundle(t *testing.T) {
+	_, err := NewHTTPClient(configs.HTTPClientConfig{CABundle: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorContains(t, err, "HTTP_CA_BUNDLE")
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0o600))
+	_, err = NewHTTPClient(configs.HTTPClientConfig{CABundle: bundle})
+	assert.ErrorContains(t, err, "PEM")
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/ratelimit"
+)
+
+// ErrRateLimited is returned without calling the upstream when a provider's
+// outbound budget is exhausted.
+var ErrRateLimited = errors.New("Limite de chamadas ao provedor atingido")
+
+// WithLimit caps the calls made to p at rate per second, with bursts of up
+// to burst, and at maxConcurrent calls in flight. While either budget is
+// exhausted Available returns ErrRateLimited, so the race skips p instead
+// of pushing the upstream into throttling or banning us; a call that gets
+// through anyway fails with ErrRateLimited. A zero rate or maxConcurrent
+// leaves that cap off.
+//
+// WithLimit goes outside WithBreaker, so rejected calls never count as
+// upstream failures; it reports the breaker's availability and state as its
+// own. Every attempt reaches the upstream, so a WithRetry inside it takes a
+// token for each retry, and gives up when there is none.
+func WithLimit(p Provider, rate float64, burst, maxConcurrent int) Provider {
+	if rate <= 0 && maxConcurrent <= 0 {
+		return p
+	}
+	limited := &limitProvider{Provider: p}
+	if rate > 0 {
+		limited.limiter = ratelimit.NewLimiter(rate, burst)
+	}
+	if maxConcurrent > 0 {
+		limited.slots = make(chan struct{}, maxConcurrent)
+	}
+	return limited
+}
+
+type limitProvider struct {
+	Provider
+	limiter *ratelimit.Limiter
+	slots   chan struct{}
+}
+
+func (p *limitProvider) Available() error {
+	if gate, ok := p.Provider.(Gate); ok {
+		if err := gate.Available(); err != nil {
+			return err
+		}
+	}
+	if p.slots != nil && len(p.slots) == cap(p.slots) {
+		return &UnavailableError{Provider: p.Name(), Err: ErrRateLimited}
+	}
+	if p.limiter != nil {
+		if wait := p.limiter.Delay(p.Name()); wait > 0 {
+			return &UnavailableError{Provider: p.Name(), Err: ErrRateLimited, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// State reports the state of the wrapped circuit breaker, if any.
+func (p *limitProvider) State() string {
+	if breaker, ok := p.Provider.(interface{ State() string }); ok {
+		return breaker.State()
+	}
+	return ""
+}
+
+func (p *limitProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+func (p *limitProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+			defer func() { <-p.slots }()
+		default:
+			return nil, ErrRateLimited
+		}
+	}
+	if p.limiter != nil {
+		if !p.limiter.Allow(p.Name()).Allowed {
+			return nil, ErrRateLimited
+		}
+		ctx = context.WithValue(ctx, retryBudgetKey{}, func() bool {
+			return p.limiter.Allow(p.Name()).Allowed
+		})
+	}
+	return p.Provider.Lookup(ctx, cep)
+}
+
+type retryBudgetKey struct{}
+
+// allowRetry takes a token for one more attempt from the outbound limit the
+// call behind ctx went through, reporting whether there was one. Calls
+// without an outbound limit may always retry.
+func allowRetry(ctx context.Context) bool {
+	allow, ok := ctx.Value(retryBudgetKey{}).(func() bool)
+	return !ok || allow()
+}
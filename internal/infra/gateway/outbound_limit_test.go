@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLimitDisabled(t *testing.T) {
+	provider := &stubProvider{name: "Stub"}
+
+	assert.Same(t, provider, WithLimit(provider, 0, 10, 0))
+}
+
+func TestWithLimitRateSkipsProviderWhenBudgetIsSpent(t *testing.T) {
+	var calls atomic.Int32
+	provider := WithLimit(countingProvider(&calls), 0.001, 2, 0)
+	gate := provider.(Gate)
+
+	for range 2 {
+		assert.NoError(t, gate.Available())
+		_, err := provider.Lookup(context.Background(), "01310100")
+		assert.NoError(t, err)
+	}
+
+	assert.ErrorIs(t, gate.Available(), ErrRateLimited)
+	_, err := provider.Lookup(context.Background(), "01310100")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestWithLimitCapsConcurrentCalls(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocking := &funcProvider{name: "Blocking", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		close(started)
+		<-release
+		return &dto.CEP{Cep: cep}, nil
+	}}
+	provider := WithLimit(blocking, 0, 0, 1)
+	gate := provider.(Gate)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		provider.Lookup(context.Background(), "01310100")
+	}()
+	<-started
+
+	assert.ErrorIs(t, gate.Available(), ErrRateLimited)
+	_, err := provider.Lookup(context.Background(), "01310100")
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	close(release)
+	<-done
+	assert.NoError(t, gate.Available())
+}
+
+func TestWithLimitKeepsBreakerVisible(t *testing.T) {
+	var calls atomic.Int32
+	breaker := WithBreaker(countingProvider(&calls, errors.New("connection refused")), 1, time.Minute)
+	provider := WithLimit(breaker, 100, 10, 0)
+	registry := NewRegistry(provider)
+
+	available, _ := registry.Available()
+	assert.Len(t, available, 1)
+	assert.Equal(t, CircuitClosed, registry.Status()[0].Circuit)
+
+	provider.Lookup(context.Background(), "01310100")
+
+	available, skipped := registry.Available()
+	assert.Empty(t, available)
+	assert.Len(t, skipped, 1)
+	assert.ErrorIs(t, skipped[0], ErrCircuitOpen)
+	assert.Equal(t, CircuitOpen, registry.Status()[0].Circuit)
+}
+
+func TestWithLimitRejectionsDoNotOpenTheCircuit(t *testing.T) {
+	var calls atomic.Int32
+	breaker := WithBreaker(countingProvider(&calls), 1, time.Minute)
+	provider := WithLimit(breaker, 0.001, 1, 0)
+
+	provider.Lookup(context.Background(), "01310100")
+	_, err := provider.Lookup(context.Background(), "01310100")
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, CircuitClosed, breaker.(interface{ State() string }).State())
+}
+
+func TestWithLimitChargesEveryRetry(t *testing.T) {
+	refused := errors.New("connection refused")
+	for burst, expected := range map[int]int32{1: 1, 3: 3, 10: 4} {
+		var calls atomic.Int32
+		provider := WithLimit(WithRetry(countingProvider(&calls, refused, refused, refused, refused), 3, 0), 0.001, burst, 0)
+
+		_, err := provider.Lookup(context.Background(), "01310100")
+
+		assert.ErrorIs(t, err, refused)
+		assert.Equal(t, expected, calls.Load(), "burst %d", burst)
+	}
+}
+
+func TestErrorTypeRateLimited(t *testing.T) {
+	assert.Equal(t, ErrorTypeRateLimited, ErrorType(ErrRateLimited))
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+)
+
+// ErrCEPNotFound is returned by providers when the upstream reports that the
+// CEP does not exist, as opposed to failing to answer.
+var ErrCEPNotFound = errors.New("CEP não encontrado")
+
+// Provider is an upstream CEP source that takes part in the race.
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, cep string) (*dto.CEP, error)
+}
+
+// Registry keeps the providers raced by the handler, in registration order,
+// and tracks the outcome of every call made through them.
+type Registry struct {
+	mu        sync.RWMutex
+	providers []*trackedProvider
+	tracker   *StatusTracker
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{tracker: NewStatusTracker()}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers = append(r.providers, &trackedProvider{Provider: p, tracker: r.tracker})
+}
+
+// Providers returns every registered provider, including those currently
+// out of the race.
+func (r *Registry) Providers() []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	providers := make([]Provider, len(r.providers))
+	for i, p := range r.providers {
+		providers[i] = p
+	}
+	return providers
+}
+
+// Available returns the providers that should take part in a race now, and
+// why each of the others, whose Gate is closed, was left out.
+func (r *Registry) Available() (providers []Provider, skipped []*UnavailableError) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	providers = make([]Provider, 0, len(r.providers))
+	for _, p := range r.providers {
+		if gate, ok := p.Provider.(Gate); ok {
+			if err := gate.Available(); err != nil {
+				var unavailable *UnavailableError
+				if !errors.As(err, &unavailable) {
+					unavailable = &UnavailableError{Provider: p.Name(), Err: err}
+				}
+				skipped = append(skipped, unavailable)
+				continue
+			}
+		}
+		providers = append(providers, p)
+	}
+	return providers, skipped
+}
+
+// LatencyP95 is the p95 latency of the named provider's recent calls,
+// counting calls cancelled because another provider won for as long as they
+// ran, and false while there are none.
+func (r *Registry) LatencyP95(name string) (time.Duration, bool) {
+	return r.tracker.LatencyP95(name)
+}
+
+// Status reports the recent success rate and latency of each provider, and
+// its circuit breaker state when it has one.
+func (r *Registry) Status() []dto.ProviderStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	status := make([]dto.ProviderStatus, len(r.providers))
+	for i, p := range r.providers {
+		status[i] = r.tracker.Snapshot(p.Name())
+		if breaker, ok := p.Provider.(interface{ State() string }); ok {
+			status[i].Circuit = breaker.State()
+		}
+	}
+	return status
+}
@@ -0,0 +1,244 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while a provider's
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("Circuito aberto: provedor temporariamente fora da corrida")
+
+// Circuit breaker states, as reported in dto.ProviderStatus.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// Gate is implemented by providers that can temporarily step out of the
+// race. Registry.Available leaves them out while Available returns an error.
+type Gate interface {
+	// Available returns nil while the provider may take part in the race,
+	// or an *UnavailableError saying why it may not.
+	Available() error
+}
+
+// UnavailableError is why a provider is out of the race: Err is
+// ErrCircuitOpen or ErrRateLimited, and RetryAfter how long until it is
+// expected back, zero when unknown.
+type UnavailableError struct {
+	Provider   string
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// WithTimeout bounds every Lookup of p, retries included, to timeout. A
+// zero timeout returns p unchanged, leaving only the race deadline.
+func WithTimeout(p Provider, timeout time.Duration) Provider {
+	if timeout <= 0 {
+		return p
+	}
+	return &timeoutProvider{Provider: p, timeout: timeout}
+}
+
+type timeoutProvider struct {
+	Provider
+	timeout time.Duration
+}
+
+func (p *timeoutProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+func (p *timeoutProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Provider.Lookup(ctx, cep)
+}
+
+// WithRetry retries transport errors of p up to retries more times. The
+// wait before attempt n is backoff*2^(n-1) with ±50% jitter, so replicas
+// that failed together do not retry in lockstep. Timeouts, non-200 answers
+// and decode errors are not retried: another attempt would most likely fail
+// the same way while eating into the race deadline.
+func WithRetry(p Provider, retries int, backoff time.Duration) Provider {
+	if retries <= 0 {
+		return p
+	}
+	return &retryProvider{Provider: p, retries: retries, backoff: backoff}
+}
+
+type retryProvider struct {
+	Provider
+	retries int
+	backoff time.Duration
+}
+
+func (p *retryProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+func (p *retryProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := p.Provider.Lookup(ctx, cep)
+		if err == nil || attempt == p.retries || ctx.Err() != nil || ErrorType(err) != ErrorTypeTransport {
+			return resp, err
+		}
+
+		wait := jitter(p.backoff << attempt)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt+1),
+			attribute.String("error", err.Error()),
+		))
+		slog.DebugContext(ctx, "nova tentativa no provedor", "provider", p.Name(), "attempt", attempt+1, "wait", wait, "error", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+
+		if !allowRetry(ctx) {
+			slog.DebugContext(ctx, "nova tentativa barrada pelo limite de saída", "provider", p.Name(), "attempt", attempt+1)
+			return nil, err
+		}
+	}
+}
+
+// jitter spreads d uniformly over [d/2, 3d/2).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d)
+}
+
+// WithBreaker opens p's circuit after threshold consecutive failures. While
+// open, Lookup fails with ErrCircuitOpen and Available returns it, so the
+// race skips p. After cooldown a single trial call is let through
+// (half-open): success closes the circuit, failure opens it again. "Not
+// found" counts as success; calls cancelled because another provider won
+// count as neither. A threshold of zero disables the breaker.
+func WithBreaker(p Provider, threshold int, cooldown time.Duration) Provider {
+	if threshold <= 0 {
+		return p
+	}
+	return &breakerProvider{Provider: p, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+type breakerProvider struct {
+	Provider
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	trial    bool
+}
+
+func (p *breakerProvider) Available() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.open {
+		return nil
+	}
+	remaining := p.cooldown - p.now().Sub(p.openedAt)
+	if !p.trial && remaining <= 0 {
+		return nil
+	}
+	return &UnavailableError{Provider: p.Name(), Err: ErrCircuitOpen, RetryAfter: max(remaining, 0)}
+}
+
+// State reports closed, open or half_open (a trial call is allowed or in
+// flight).
+func (p *breakerProvider) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case !p.open:
+		return CircuitClosed
+	case p.trial || p.now().Sub(p.openedAt) >= p.cooldown:
+		return CircuitHalfOpen
+	default:
+		return CircuitOpen
+	}
+}
+
+func (p *breakerProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+func (p *breakerProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	trial, ok := p.acquire()
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := p.Provider.Lookup(ctx, cep)
+	p.release(ctx, trial, err)
+	return resp, err
+}
+
+// acquire reports whether a call may go through and whether it is the
+// half-open trial.
+func (p *breakerProvider) acquire() (trial bool, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.open {
+		return false, true
+	}
+	if p.trial || p.now().Sub(p.openedAt) < p.cooldown {
+		return false, false
+	}
+	p.trial = true
+	return true, true
+}
+
+func (p *breakerProvider) release(ctx context.Context, trial bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if trial {
+		p.trial = false
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return
+	case err == nil || errors.Is(err, ErrCEPNotFound):
+		if p.open {
+			slog.InfoContext(ctx, "circuito fechado", "provider", p.Name())
+		}
+		p.open = false
+		p.failures = 0
+	case trial:
+		p.openedAt = p.now()
+		slog.WarnContext(ctx, "circuito reaberto", "provider", p.Name(), "cooldown", p.cooldown, "error", err)
+	case !p.open:
+		p.failures++
+		if p.failures >= p.threshold {
+			p.open = true
+			p.openedAt = p.now()
+			slog.WarnContext(ctx, "circuito aberto", "provider", p.Name(), "failures", p.failures, "cooldown", p.cooldown, "error", err)
+		}
+	}
+}
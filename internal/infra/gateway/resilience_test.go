@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProvider answers with the next error of errs on each call, then
+// succeeds.
+func countingProvider(calls *atomic.Int32, errs ...error) *funcProvider {
+	return &funcProvider{name: "Counting", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		n := int(calls.Add(1))
+		if n <= len(errs) && errs[n-1] != nil {
+			return nil, errs[n-1]
+		}
+		return &dto.CEP{Cep: cep}, nil
+	}}
+}
+
+func TestWithTimeout(t *testing.T) {
+	slow := &funcProvider{name: "Slow", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	assert.Same(t, slow, WithTimeout(slow, 0))
+
+	start := time.Now()
+	_, err := WithTimeout(slow, 10*time.Millisecond).Lookup(context.Background(), "01310100")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWithRetryRetriesTransportErrors(t *testing.T) {
+	var calls atomic.Int32
+	provider := WithRetry(countingProvider(&calls, errors.New("connection reset"), errors.New("connection reset")), 2, time.Millisecond)
+
+	resp, err := provider.Lookup(context.Background(), "01310100")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "01310100", resp.Cep)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestWithRetryGivesUpAfterRetries(t *testing.T) {
+	var calls atomic.Int32
+	transportErr := errors.New("connection reset")
+	provider := WithRetry(countingProvider(&calls, transportErr, transportErr, transportErr), 1, time.Millisecond)
+
+	_, err := provider.Lookup(context.Background(), "01310100")
+
+	assert.ErrorIs(t, err, transportErr)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestWithRetrySkipsNonTransportErrors(t *testing.T) {
+	for _, err := range []error{ErrCEPNotFound, &StatusError{StatusCode: 500}, &DecodeError{Err: errors.New("bad json")}, context.DeadlineExceeded} {
+		var calls atomic.Int32
+		provider := WithRetry(countingProvider(&calls, err), 3, time.Millisecond)
+
+		_, got := provider.Lookup(context.Background(), "01310100")
+
+		assert.ErrorIs(t, got, err)
+		assert.Equal(t, int32(1), calls.Load(), err.Error())
+	}
+}
+
+func TestWithRetryStopsWhenContextEnds(t *testing.T) {
+	var calls atomic.Int32
+	provider := WithRetry(countingProvider(&calls, errors.New("connection reset")), 3, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestJitter(t *testing.T) {
+	assert.Zero(t, jitter(0))
+	for range 100 {
+		d := jitter(100 * time.Millisecond)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.Less(t, d, 150*time.Millisecond)
+	}
+}
+
+func TestWithBreakerOpensAndHalfOpens(t *testing.T) {
+	var calls atomic.Int32
+	upstreamErr := &StatusError{StatusCode: 503}
+	now := time.Now()
+	provider := WithBreaker(countingProvider(&calls, upstreamErr, upstreamErr, upstreamErr), 2, time.Minute).(*breakerProvider)
+	provider.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	provider.Lookup(ctx, "01310100")
+	assert.NoError(t, provider.Available())
+	assert.Equal(t, CircuitClosed, provider.State())
+
+	provider.Lookup(ctx, "01310100")
+	assert.ErrorIs(t, provider.Available(), ErrCircuitOpen)
+	assert.Equal(t, CircuitOpen, provider.State())
+
+	_, err := provider.Lookup(ctx, "01310100")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls.Load(), "open circuit must not call the upstream")
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, provider.Available())
+	assert.Equal(t, CircuitHalfOpen, provider.State())
+
+	_, err = provider.Lookup(ctx, "01310100")
+	assert.ErrorIs(t, err, upstreamErr, "failed trial")
+	assert.ErrorIs(t, provider.Available(), ErrCircuitOpen)
+
+	now = now.Add(time.Minute)
+	_, err = provider.Lookup(ctx, "01310100")
+	assert.NoError(t, err, "successful trial")
+	assert.Equal(t, CircuitClosed, provider.State())
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestWithBreakerAllowsSingleTrial(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	provider := WithBreaker(&funcProvider{name: "Trial", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		close(started)
+		<-release
+		return &dto.CEP{Cep: cep}, nil
+	}}, 1, 0).(*breakerProvider)
+	provider.open = true
+
+	go provider.Lookup(context.Background(), "01310100")
+	<-started
+
+	assert.ErrorIs(t, provider.Available(), ErrCircuitOpen)
+	_, err := provider.Lookup(context.Background(), "01310100")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	close(release)
+}
+
+func TestWithBreakerIgnoresNotFoundAndCancelledCalls(t *testing.T) {
+	var calls atomic.Int32
+	provider := WithBreaker(countingProvider(&calls, ErrCEPNotFound, context.Canceled, ErrCEPNotFound), 1, time.Minute).(*breakerProvider)
+
+	provider.Lookup(context.Background(), "01310100")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	provider.Lookup(ctx, "01310100")
+	provider.Lookup(context.Background(), "01310100")
+
+	assert.Equal(t, CircuitClosed, provider.State())
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRegistryAvailableSkipsOpenCircuits(t *testing.T) {
+	var calls atomic.Int32
+	failing := WithBreaker(countingProvider(&calls, errors.New("connection refused")), 1, time.Minute)
+	healthy := &stubProvider{name: "Healthy"}
+	registry := NewRegistry(failing, healthy)
+
+	available, skipped := registry.Available()
+	assert.Len(t, available, 2)
+	assert.Empty(t, skipped)
+	registry.Providers()[0].Lookup(context.Background(), "01310100")
+
+	available, skipped = registry.Available()
+	assert.Len(t, available, 1)
+	assert.Equal(t, "Healthy", available[0].Name())
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, failing.Name(), skipped[0].Provider)
+	assert.ErrorIs(t, skipped[0], ErrCircuitOpen)
+	assert.InDelta(t, time.Minute, skipped[0].RetryAfter, float64(time.Second))
+	assert.Len(t, registry.Providers(), 2)
+
+	status := registry.Status()
+	assert.Equal(t, CircuitOpen, status[0].Circuit)
+	assert.Empty(t, status[1].Circuit)
+}
+
+func TestErrorTypeCircuitOpen(t *testing.T) {
+	assert.Equal(t, ErrorTypeCircuitOpen, ErrorType(ErrCircuitOpen))
+}
@@ -0,0 +1,284 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statusWindow is how many of the latest calls per provider are kept for
+// success rate and latency figures.
+const statusWindow = 100
+
+// probeCEP is a long-lived CEP (Praça da Sé, São Paulo) used to check that a
+// provider answers at all.
+const probeCEP = "01001000"
+
+// Prober is implemented by providers that have a cheaper health check than a
+// regular lookup.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+// wrapper is implemented by the resilience decorators, to reach the provider
+// they wrap.
+type wrapper interface {
+	Unwrap() Provider
+}
+
+// Probe checks that p is reachable. It goes around p's outbound limit,
+// circuit breaker, timeout and retries, so a probe neither spends the
+// provider's budget nor trips its breaker. An upstream answering "not
+// found" is still reachable.
+func Probe(ctx context.Context, p Provider) error {
+	for {
+		if prober, ok := p.(Prober); ok {
+			return prober.Probe(ctx)
+		}
+		w, ok := p.(wrapper)
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+	_, err := p.Lookup(ctx, probeCEP)
+	if errors.Is(err, ErrCEPNotFound) {
+		return nil
+	}
+	return err
+}
+
+type outcome struct {
+	latency time.Duration
+	failed  bool
+}
+
+type providerStats struct {
+	outcomes    []outcome
+	next        int
+	lastSuccess time.Time
+	lastError   string
+	// durations also counts calls cancelled because another provider won,
+	// for how long they ran, so a slow provider that keeps losing still
+	// shows up as slow in LatencyP95.
+	durations    []time.Duration
+	nextDuration int
+}
+
+func (s *providerStats) addDuration(d time.Duration) {
+	if len(s.durations) < statusWindow {
+		s.durations = append(s.durations, d)
+		return
+	}
+	s.durations[s.nextDuration] = d
+	s.nextDuration = (s.nextDuration + 1) % statusWindow
+}
+
+// StatusTracker keeps a sliding window of call outcomes per provider.
+type StatusTracker struct {
+	mu    sync.Mutex
+	stats map[string]*providerStats
+	now   func() time.Time
+}
+
+func NewStatusTracker() *StatusTracker {
+	return &StatusTracker{
+		stats: make(map[string]*providerStats),
+		now:   time.Now,
+	}
+}
+
+// Record stores the outcome of one call. "Not found" answers count as
+// successes since the provider did its job.
+func (t *StatusTracker) Record(name string, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.statsFor(name)
+	stats.addDuration(latency)
+	t.addOutcome(stats, latency, err)
+}
+
+// RecordProbe stores the outcome of one readiness probe. Probes bypass the
+// resilience decorators and always ask for the same CEP, so they are left
+// out of LatencyP95.
+func (t *StatusTracker) RecordProbe(name string, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.addOutcome(t.statsFor(name), latency, err)
+}
+
+func (t *StatusTracker) addOutcome(stats *providerStats, latency time.Duration, err error) {
+	failed := err != nil && !errors.Is(err, ErrCEPNotFound)
+	if failed {
+		stats.lastError = err.Error()
+	} else {
+		stats.lastSuccess = t.now()
+	}
+
+	if len(stats.outcomes) < statusWindow {
+		stats.outcomes = append(stats.outcomes, outcome{latency: latency, failed: failed})
+		return
+	}
+	stats.outcomes[stats.next] = outcome{latency: latency, failed: failed}
+	stats.next = (stats.next + 1) % statusWindow
+}
+
+// RecordCancelled stores how long a call ran before it was cancelled because
+// another provider won. It only counts towards LatencyP95, as a lower bound
+// of how long the call would have taken.
+func (t *StatusTracker) RecordCancelled(name string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statsFor(name).addDuration(latency)
+}
+
+func (t *StatusTracker) statsFor(name string) *providerStats {
+	stats, ok := t.stats[name]
+	if !ok {
+		stats = &providerStats{outcomes: make([]outcome, 0, statusWindow)}
+		t.stats[name] = stats
+	}
+	return stats
+}
+
+// LatencyP95 is the p95 latency of the provider's recent calls, cancelled
+// ones included, and false while there are none.
+func (t *StatusTracker) LatencyP95(name string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.stats[name]
+	if !ok || len(stats.durations) == 0 {
+		return 0, false
+	}
+	durations := slices.Clone(stats.durations)
+	slices.Sort(durations)
+	return durations[(len(durations)*95-1)/100], true
+}
+
+func (t *StatusTracker) Snapshot(name string) dto.ProviderStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := dto.ProviderStatus{Name: name}
+	stats, ok := t.stats[name]
+	if !ok || len(stats.outcomes) == 0 {
+		return status
+	}
+
+	var total time.Duration
+	successes := 0
+	latencies := make([]time.Duration, 0, len(stats.outcomes))
+	for _, o := range stats.outcomes {
+		total += o.latency
+		latencies = append(latencies, o.latency)
+		if !o.failed {
+			successes++
+		}
+	}
+	slices.Sort(latencies)
+
+	status.Requests = len(stats.outcomes)
+	status.SuccessRate = float64(successes) / float64(status.Requests)
+	status.AvgLatencyMs = milliseconds(total / time.Duration(status.Requests))
+	status.P95LatencyMs = milliseconds(latencies[(len(latencies)*95-1)/100])
+	status.LastError = stats.lastError
+	if !stats.lastSuccess.IsZero() {
+		lastSuccess := stats.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	return status
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Outcomes logged for a provider call besides the error types.
+const (
+	OutcomeOK        = "ok"
+	OutcomeCancelled = "cancelled"
+)
+
+// trackedProvider records every call of the wrapped provider in the status
+// tracker and in the Prometheus metrics, except calls cancelled because
+// another provider already won the race, which only count towards
+// LatencyP95. Calls rejected by the outbound limit or an open circuit only
+// count in cep_provider_errors_total. Every call, cancelled or not, gets its
+// own span.
+type trackedProvider struct {
+	Provider
+	tracker *StatusTracker
+}
+
+func (p *trackedProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "provider.lookup", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cep.provider", p.Name()), tracing.CEP(cep)))
+	defer span.End()
+
+	start := time.Now()
+	resp, err := p.Provider.Lookup(ctx, cep)
+	p.record(ctx, span, start, err, false)
+	return resp, err
+}
+
+func (p *trackedProvider) Probe(ctx context.Context) error {
+	ctx, span := tracing.Tracer().Start(ctx, "provider.probe", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cep.provider", p.Name())))
+	defer span.End()
+
+	start := time.Now()
+	err := Probe(ctx, p.Provider)
+	p.record(ctx, span, start, err, true)
+	return err
+}
+
+// record logs and stores the outcome of a call; probe calls stay out of
+// LatencyP95.
+func (p *trackedProvider) record(ctx context.Context, span trace.Span, start time.Time, err error, probe bool) {
+	elapsed := time.Since(start)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		span.SetAttributes(attribute.Bool("cep.race.cancelled", true))
+		slog.InfoContext(ctx, "consulta ao provedor", "provider", p.Name(), "outcome", OutcomeCancelled, "duration", elapsed)
+		if !probe {
+			p.tracker.RecordCancelled(p.Name(), elapsed)
+		}
+		return
+	}
+
+	switch {
+	case err == nil:
+		slog.InfoContext(ctx, "consulta ao provedor", "provider", p.Name(), "outcome", OutcomeOK, "duration", elapsed)
+	case errors.Is(err, ErrCEPNotFound):
+		slog.InfoContext(ctx, "consulta ao provedor", "provider", p.Name(), "outcome", ErrorTypeNotFound, "duration", elapsed)
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, ErrorType(err))
+		slog.WarnContext(ctx, "consulta ao provedor", "provider", p.Name(), "outcome", ErrorType(err), "duration", elapsed, "error", err)
+	}
+
+	if err != nil {
+		metrics.ProviderErrors.WithLabelValues(p.Name(), ErrorType(err)).Inc()
+	}
+	// Calls turned away before reaching the upstream say nothing about its
+	// health or latency.
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	if probe {
+		p.tracker.RecordProbe(p.Name(), elapsed, err)
+	} else {
+		p.tracker.Record(p.Name(), elapsed, err)
+	}
+	metrics.ProviderDuration.WithLabelValues(p.Name()).Observe(elapsed.Seconds())
+}
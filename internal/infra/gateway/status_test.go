@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type funcProvider struct {
+	name   string
+	lookup func(ctx context.Context, cep string) (*dto.CEP, error)
+}
+
+func (f *funcProvider) Name() string {
+	return f.name
+}
+
+func (f *funcProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	return f.lookup(ctx, cep)
+}
+
+func TestStatusTrackerSnapshot(t *testing.T) {
+	tracker := NewStatusTracker()
+
+	for i := 1; i <= 20; i++ {
+		tracker.Record("BrasilAPI", time.Duration(i)*time.Millisecond, nil)
+	}
+	tracker.Record("BrasilAPI", time.Millisecond*50, errors.New("API retornou status 500"))
+	tracker.Record("BrasilAPI", time.Millisecond*30, ErrCEPNotFound)
+
+	status := tracker.Snapshot("BrasilAPI")
+
+	assert.Equal(t, "BrasilAPI", status.Name)
+	assert.Equal(t, 22, status.Requests)
+	assert.InDelta(t, 21.0/22.0, status.SuccessRate, 0.0001)
+	assert.InDelta(t, 290.0/22.0, status.AvgLatencyMs, 0.0001)
+	assert.Equal(t, 30.0, status.P95LatencyMs)
+	assert.Equal(t, "API retornou status 500", status.LastError)
+	assert.NotNil(t, status.LastSuccess)
+}
+
+func TestStatusTrackerKeepsSlidingWindow(t *testing.T) {
+	tracker := NewStatusTracker()
+
+	for range statusWindow {
+		tracker.Record("ViaCEP", time.Millisecond, errors.New("timeout"))
+	}
+	for range statusWindow / 2 {
+		tracker.Record("ViaCEP", time.Millisecond, nil)
+	}
+
+	status := tracker.Snapshot("ViaCEP")
+
+	assert.Equal(t, statusWindow, status.Requests)
+	assert.InDelta(t, 0.5, status.SuccessRate, 0.0001)
+}
+
+func TestStatusTrackerLatencyP95CountsCancelledCalls(t *testing.T) {
+	tracker := NewStatusTracker()
+
+	_, ok := tracker.LatencyP95("BrasilAPI")
+	assert.False(t, ok)
+
+	for range 10 {
+		tracker.Record("BrasilAPI", time.Millisecond*10, nil)
+		tracker.RecordCancelled("BrasilAPI", time.Millisecond*500)
+	}
+
+	p95, ok := tracker.LatencyP95("BrasilAPI")
+	assert.True(t, ok)
+	assert.Equal(t, time.Millisecond*500, p95)
+
+	status := tracker.Snapshot("BrasilAPI")
+	assert.Equal(t, 10, status.Requests)
+	assert.Equal(t, 10.0, status.P95LatencyMs)
+}
+
+func TestStatusTrackerSnapshotUnknownProvider(t *testing.T) {
+	status := NewStatusTracker().Snapshot("OpenCEP")
+
+	assert.Equal(t, dto.ProviderStatus{Name: "OpenCEP"}, status)
+}
+
+func TestRegistryStatusIgnoresCancelledLosers(t *testing.T) {
+	registry := NewRegistry(&funcProvider{name: "Loser", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}})
+	provider := registry.Providers()[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	provider.Lookup(ctx, "01310100")
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	provider.Lookup(ctx, "01310100")
+
+	status := registry.Status()
+	assert.Len(t, status, 1)
+	assert.Equal(t, 1, status[0].Requests)
+	assert.Equal(t, 0.0, status[0].SuccessRate)
+}
+
+func TestProbe(t *testing.T) {
+	reachable := &funcProvider{name: "A", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		return nil, ErrCEPNotFound
+	}}
+	unreachable := &funcProvider{name: "B", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		return nil, errors.New("connection refused")
+	}}
+
+	assert.NoError(t, Probe(context.Background(), reachable))
+	assert.Error(t, Probe(context.Background(), unreachable))
+}
+
+func TestProbeGoesAroundResilienceDecorators(t *testing.T) {
+	var calls int
+	bare := &funcProvider{name: "Probed", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	}}
+	provider := WithLimit(WithBreaker(WithRetry(WithTimeout(bare, time.Second), 2, time.Millisecond), 1, time.Minute), 0.001, 1, 0)
+
+	assert.Error(t, Probe(context.Background(), provider))
+	assert.Error(t, Probe(context.Background(), provider))
+
+	assert.Equal(t, 2, calls, "a probe is neither retried nor turned away")
+	assert.NoError(t, provider.(Gate).Available(), "probes spend no budget and leave the circuit closed")
+}
+
+func TestRegistryKeepsProbesOutOfLatencyP95(t *testing.T) {
+	registry := NewRegistry(&funcProvider{name: "Probed", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		return nil, ErrCEPNotFound
+	}})
+
+	assert.NoError(t, registry.Providers()[0].(Prober).Probe(context.Background()))
+
+	_, ok := registry.LatencyP95("Probed")
+	assert.False(t, ok)
+	assert.Equal(t, 1, registry.Status()[0].Requests)
+}
+
+func TestRegistryRecordsProviderMetrics(t *testing.T) {
+	registry := NewRegistry(&funcProvider{name: "MetricsTest", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		return nil, &StatusError{StatusCode: 502}
+	}})
+	errorsBefore := testutil.ToFloat64(metrics.ProviderErrors.WithLabelValues("MetricsTest", ErrorTypeStatus))
+
+	registry.Providers()[0].Lookup(context.Background(), "01310100")
+
+	assert.Equal(t, errorsBefore+1, testutil.ToFloat64(metrics.ProviderErrors.WithLabelValues("MetricsTest", ErrorTypeStatus)))
+	assert.Positive(t, testutil.CollectAndCount(metrics.ProviderDuration, "cep_provider_request_duration_seconds"))
+}
+
+func TestRegistryKeepsRejectedCallsOutOfStatus(t *testing.T) {
+	var calls int
+	registry := NewRegistry(&funcProvider{name: "RejectedTest", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+		calls++
+		if calls == 1 {
+			return nil, ErrRateLimited
+		}
+		return nil, ErrCircuitOpen
+	}})
+	seriesBefore := testutil.CollectAndCount(metrics.ProviderDuration)
+	rateLimitedBefore := testutil.ToFloat64(metrics.ProviderErrors.WithLabelValues("RejectedTest", ErrorTypeRateLimited))
+	circuitOpenBefore := testutil.ToFloat64(metrics.ProviderErrors.WithLabelValues("RejectedTest", ErrorTypeCircuitOpen))
+
+	registry.Providers()[0].Lookup(context.Background(), "01310100")
+	registry.Providers()[0].Lookup(context.Background(), "01310100")
+
+	assert.Equal(t, 0, registry.Status()[0].Requests)
+	assert.Equal(t, seriesBefore, testutil.CollectAndCount(metrics.ProviderDuration))
+	assert.Equal(t, rateLimitedBefore+1, testutil.ToFloat64(metrics.ProviderErrors.WithLabelValues("RejectedTest", ErrorTypeRateLimited)))
+	assert.Equal(t, circuitOpenBefore+1, testutil.ToFloat64(metrics.ProviderErrors.WithLabelValues("RejectedTest", ErrorTypeCircuitOpen)))
+}
+
+func TestRegistryTracesEveryProviderCall(t *testing.T) {
+	exporter, restore := tracing.InstallInMemory()
+	defer restore()
+
+	registry := NewRegistry(
+		&funcProvider{name: "Failing", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+			return nil, &StatusError{StatusCode: 500}
+		}},
+		&funcProvider{name: "Loser", lookup: func(ctx context.Context, cep string) (*dto.CEP, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}},
+	)
+	providers := registry.Providers()
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "parent")
+	providers[0].Lookup(ctx, "01310100")
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	providers[1].Lookup(ctx, "01310100")
+	parent.End()
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		assert.Equal(t, "provider.lookup", span.Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Contains(t, span.Attributes, attribute.String("cep", "01310100"))
+	}
+	assert.Contains(t, spans[0].Attributes, attribute.String("cep.provider", "Failing"))
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Contains(t, spans[1].Attributes, attribute.String("cep.provider", "Loser"))
+	assert.Contains(t, spans[1].Attributes, attribute.Bool("cep.race.cancelled", true))
+	assert.Equal(t, codes.Unset, spans[1].Status.Code)
+}
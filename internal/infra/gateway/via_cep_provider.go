@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/entity"
+)
+
+const ViaCEPName = "ViaCEP"
+
+type ViaCEPProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewViaCEPProvider(url string, client *http.Client) *ViaCEPProvider {
+	return &ViaCEPProvider{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *ViaCEPProvider) Name() string {
+	return ViaCEPName
+}
+
+func (p *ViaCEPProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
+	var apiResp entity.ViaCEP
+	if err := getJSON(ctx, p.client, ViaCEPName, fmt.Sprintf(p.url, cep), &apiResp); err != nil {
+		return nil, err
+	}
+
+	if apiResp.Erro {
+		return nil, ErrCEPNotFound
+	}
+
+	return &dto.CEP{
+		Cep:         apiResp.Cep,
+		Logradouro:  apiResp.Logradouro,
+		Complemento: apiResp.Complemento,
+		Unidade:     apiResp.Unidade,
+		Bairro:      apiResp.Bairro,
+		Localidade:  apiResp.Localidade,
+		Uf:          apiResp.Uf,
+		Estado:      apiResp.Uf,
+		Regiao:      apiResp.Regiao,
+		Ibge:        apiResp.Ibge,
+		Gia:         apiResp.Gia,
+		Ddd:         apiResp.Ddd,
+		Siafi:       apiResp.Siafi,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViaCEPProviderLookupSuccess(t *testing.T) {
+	mockResponse := entity.ViaCEP{
+		Cep:         "01310-100",
+		Logradouro:  "Avenida Paulista",
+		Complemento: "",
+		Unidade:     "",
+		Bairro:      "Bela Vista",
+		Localidade:  "São Paulo",
+		Uf:          "SP",
+		Estado:      "SP",
+		Regiao:      "Sudeste",
+		Ibge:        "3550308",
+		Gia:         "1004",
+		Ddd:         "11",
+		Siafi:       "7107",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(mockResponse)
+	}))
+	defer server.Close()
+
+	provider := NewViaCEPProvider(server.URL+"/%s", server.Client())
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, "01310-100", result.Cep)
+	assert.Equal(t, "Avenida Paulista", result.Logradouro)
+	assert.Equal(t, "Bela Vista", result.Bairro)
+	assert.Equal(t, "São Paulo", result.Localidade)
+	assert.Equal(t, "SP", result.Uf)
+	assert.Equal(t, "SP", result.Estado)
+	assert.Equal(t, "Sudeste", result.Regiao)
+	assert.Equal(t, "3550308", result.Ibge)
+	assert.Equal(t, "1004", result.Gia)
+	assert.Equal(t, "11", result.Ddd)
+	assert.Equal(t, "7107", result.Siafi)
+}
+
+func TestViaCEPProviderLookupHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	provider := NewViaCEPProvider(server.URL+"/%s", server.Client())
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "00000000")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "API retornou status 500")
+}
+
+func TestViaCEPProviderLookupNotFound(t *testing.T) {
+	bodies := []string{`{"erro": true}`, `{"erro": "true"}`}
+
+	for _, body := range bodies {
+		t.Run(body, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			provider := NewViaCEPProvider(server.URL+"/%s", server.Client())
+
+			result, err := provider.Lookup(context.Background(), "99999999")
+
+			assert.ErrorIs(t, err, ErrCEPNotFound)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestViaCEPProviderLookupInvalidJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{ invalid json }"))
+	}))
+	defer server.Close()
+
+	provider := NewViaCEPProvider(server.URL+"/%s", server.Client())
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestViaCEPProviderLookupContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := NewViaCEPProvider(server.URL+"/%s", server.Client())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestViaCEPProviderLookupInvalidURL(t *testing.T) {
+	provider := NewViaCEPProvider("://invalid-url", http.DefaultClient)
+
+	ctx := context.Background()
+	result, err := provider.Lookup(ctx, "01310100")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
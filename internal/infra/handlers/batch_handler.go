@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/ratelimit"
+)
+
+const (
+	batchErrorInvalid  = "invalid"
+	batchErrorNotFound = "not_found"
+	batchErrorTimeout  = "timeout"
+	batchErrorUpstream = "upstream_error"
+	batchErrorLimited  = "rate_limited"
+)
+
+var (
+	errInvalidBatchBody   = errors.New("Corpo da requisição deve ser uma lista JSON de CEPs")
+	errBatchItemNotString = errors.New("CEP deve ser uma string JSON")
+)
+
+// BatchCEP resolves a JSON array of CEPs with at most
+// config.BatchConcurrency lookups in flight, answering per-item results in
+// the same order as the request. Each CEP costs a rate limit token; the
+// request's own token pays for the first and the rest are charged up front,
+// all or nothing; batches larger than the client's burst are invalid.
+func (h *CepHandler) BatchCEP(w http.ResponseWriter, r *http.Request) {
+	// A rate limited client can never pay for more lookups than its burst,
+	// so a larger batch is refused outright rather than told to retry.
+	maxSize := h.config.BatchMaxSize
+	if burst := ratelimit.Burst(r.Context()); burst > 0 && burst < maxSize {
+		maxSize = burst
+	}
+
+	items, err := decodeBatch(r.Body, maxSize)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, err)
+		return
+	}
+
+	// The middleware's RateLimit-* headers predate this charge, so they are
+	// replaced whenever it took tokens, or tried to.
+	decision := ratelimit.Charge(r.Context(), len(items)-1)
+	if decision.Limit > 0 {
+		ratelimit.SetHeaders(w, decision)
+	}
+	if !decision.Allowed {
+		RateLimited(w, r)
+		return
+	}
+
+	// A large batch can take longer than the server's write timeout, which
+	// would drop the whole response, so it is lifted; a client that gives up
+	// disconnects instead, and no more lookups are started.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	ctx := r.Context()
+
+	results := make([]*dto.BatchResult, len(items))
+	sem := make(chan struct{}, max(h.config.BatchConcurrency, 1))
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		var cep string
+		if err := json.Unmarshal(item, &cep); err != nil {
+			results[i] = &dto.BatchResult{Cep: string(item), Error: &dto.BatchError{Code: batchErrorInvalid, Message: errBatchItemNotString.Error()}}
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, cep string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = h.resolve(ctx, cep)
+		}(i, cep)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&dto.BatchResponse{Results: results})
+}
+
+// decodeBatch reads the JSON array of CEPs one item at a time, giving up as
+// soon as it holds more than maxSize so an oversized body is never buffered.
+// Items are returned undecoded, so one that is not a string fails alone.
+func decodeBatch(body io.Reader, maxSize int) ([]json.RawMessage, error) {
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, errInvalidBatchBody
+	}
+	if token == nil {
+		return nil, nil
+	}
+	if token != json.Delim('[') {
+		return nil, errInvalidBatchBody
+	}
+
+	var items []json.RawMessage
+	for decoder.More() {
+		if len(items) == maxSize {
+			return nil, fmt.Errorf("Máximo de %d CEPs por requisição", maxSize)
+		}
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil, errInvalidBatchBody
+		}
+		items = append(items, item)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, errInvalidBatchBody
+	}
+	return items, nil
+}
+
+// resolve validates and looks up a single CEP, reporting failures in the
+// result instead of as an HTTP status.
+func (h *CepHandler) resolve(ctx context.Context, cep string) *dto.BatchResult {
+	result := &dto.BatchResult{Cep: cep}
+
+	normalized, err := parseCEP(cep)
+	if err != nil {
+		result.Error = &dto.BatchError{Code: batchErrorInvalid, Message: err.Error()}
+		return result
+	}
+
+	res, _, err := h.lookup(ctx, normalized)
+	if err != nil {
+		result.Error = &dto.BatchError{Code: batchErrorCode(err), Message: err.Error()}
+		return result
+	}
+
+	result.Data = dto.NewAddress(res.Data, res.Api)
+	return result
+}
+
+func batchErrorCode(err error) string {
+	switch {
+	case errors.Is(err, gateway.ErrCEPNotFound):
+		return batchErrorNotFound
+	case errors.Is(err, errAllProvidersFailed):
+		return batchErrorUpstream
+	default:
+		return batchErrorTimeout
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/cache"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCepHandlerBatchCEPPerItemResults(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	config := &configs.Config{
+		Timeout:          time.Millisecond * 50,
+		BatchMaxSize:     10,
+		BatchConcurrency: 2,
+	}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewNoop(), config)
+
+	provider.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100", Rua: "Avenida Paulista", Estado: "SP"}, nil)
+	provider.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+	provider.On("Lookup", mock.Anything, "20040002").Return(nil, errors.New("API error"))
+	provider.On("Lookup", mock.Anything, "01153000").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 100)
+	}).Return(nil, errors.New("timeout"))
+
+	body := `["01310-100", "abc", "99999999", "20040002", "01153000", "00000000"]`
+	req := httptest.NewRequest("POST", "/ceps", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	handler.BatchCEP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var response dto.BatchResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Results, 6)
+
+	assert.Equal(t, "01310-100", response.Results[0].Cep)
+	assert.Nil(t, response.Results[0].Error)
+	assert.Equal(t, "Avenida Paulista", response.Results[0].Data.Street)
+	assert.Equal(t, gateway.BrasilAPIName, response.Results[0].Data.Source)
+
+	assert.Equal(t, batchErrorInvalid, response.Results[1].Error.Code)
+	assert.Equal(t, batchErrorNotFound, response.Results[2].Error.Code)
+	assert.Equal(t, batchErrorUpstream, response.Results[3].Error.Code)
+	assert.Equal(t, batchErrorTimeout, response.Results[4].Error.Code)
+	assert.Equal(t, batchErrorInvalid, response.Results[5].Error.Code)
+	for _, result := range response.Results[1:] {
+		assert.Nil(t, result.Data)
+	}
+
+	provider.AssertNotCalled(t, "Lookup", mock.Anything, "abc")
+}
+
+func TestCepHandlerBatchCEPBoundsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	config := &configs.Config{
+		Timeout:          time.Second,
+		BatchMaxSize:     20,
+		BatchConcurrency: 3,
+	}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewNoop(), config)
+
+	provider.On("Lookup", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		current := inFlight.Add(1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 10)
+		inFlight.Add(-1)
+	}).Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	// Distinct CEPs, so that request coalescing does not merge lookups.
+	ceps := make([]string, 12)
+	for i := range ceps {
+		ceps[i] = fmt.Sprintf("013101%02d", i)
+	}
+	body, _ := json.Marshal(ceps)
+
+	req := httptest.NewRequest("POST", "/ceps", strings.NewReader(string(body)))
+	recorder := httptest.NewRecorder()
+
+	handler.BatchCEP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	provider.AssertNumberOfCalls(t, "Lookup", 12)
+}
+
+func TestCepHandlerBatchCEPStopsDispatchingWhenClientLeaves(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	config := &configs.Config{
+		Timeout:          time.Second,
+		BatchMaxSize:     20,
+		BatchConcurrency: 1,
+	}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewNoop(), config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	provider.On("Lookup", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		cancel()
+	}).Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	body, _ := json.Marshal([]string{"01310100", "01310101", "01310102", "01310103"})
+	req := httptest.NewRequest("POST", "/ceps", strings.NewReader(string(body))).WithContext(ctx)
+	recorder := httptest.NewRecorder()
+
+	handler.BatchCEP(recorder, req)
+
+	provider.AssertNumberOfCalls(t, "Lookup", 1)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestCepHandlerBatchCEPReportsNonStringItems(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	req := httptest.NewRequest("POST", "/ceps", strings.NewReader(`["01310100", 5, {"cep": "01310100"}]`))
+	recorder := httptest.NewRecorder()
+
+	handler.BatchCEP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response dto.BatchResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Results, 3)
+	assert.Nil(t, response.Results[0].Error)
+	assert.Equal(t, "5", response.Results[1].Cep)
+	assert.Equal(t, batchErrorInvalid, response.Results[1].Error.Code)
+	assert.Equal(t, `{"cep": "01310100"}`, response.Results[2].Cep)
+	assert.Equal(t, batchErrorInvalid, response.Results[2].Error.Code)
+}
+
+func TestCepHandlerBatchCEPInvalidBody(t *testing.T) {
+	handler := setupHandler(setupProviders())
+
+	for _, body := range []string{`{"cep": "01310100"}`, `["01310100"`, `["01310100" 5]`} {
+		req := httptest.NewRequest("POST", "/ceps", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+
+		handler.BatchCEP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+		assert.Equal(t, CodeInvalidRequest, decodeError(t, recorder).Code)
+		assert.Contains(t, recorder.Body.String(), "lista JSON de CEPs")
+	}
+}
+
+func TestCepHandlerBatchCEPTooManyItems(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	ceps := make([]string, 101)
+	body, _ := json.Marshal(ceps)
+
+	req := httptest.NewRequest("POST", "/ceps", strings.NewReader(string(body)))
+	recorder := httptest.NewRecorder()
+
+	handler.BatchCEP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, CodeInvalidRequest, decodeError(t, recorder).Code)
+	assert.Contains(t, recorder.Body.String(), "Máximo de 100 CEPs")
+	brasilAPI.AssertNotCalled(t, "Lookup")
+}
+
+// endlessBatch yields a JSON array of CEPs that never closes.
+type endlessBatch struct{ started bool }
+
+func (b *endlessBatch) Read(p []byte) (int, error) {
+	if !b.started {
+		b.started = true
+		return copy(p, "["), nil
+	}
+	return copy(p, `"01310100",`), nil
+}
+
+func TestCepHandlerBatchCEPStopsReadingOversizedBody(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	req := httptest.NewRequest("POST", "/ceps", &endlessBatch{})
+	recorder := httptest.NewRecorder()
+
+	handler.BatchCEP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Máximo de 100 CEPs")
+	brasilAPI.AssertNotCalled(t, "Lookup")
+}
+
+func TestCepHandlerBatchCEPChargesRateLimitPerCEP(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	config := &configs.Config{
+		Timeout:          time.Second,
+		BatchMaxSize:     20,
+		BatchConcurrency: 3,
+		RateLimitRPS:     0.001,
+		RateLimitBurst:   5,
+	}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewNoop(), config)
+	limited := ratelimit.Middleware(config, RateLimited)(http.HandlerFunc(handler.BatchCEP))
+
+	provider.On("Lookup", mock.Anything, mock.Anything).Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	post := func(ceps ...string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ceps)
+		recorder := httptest.NewRecorder()
+		limited.ServeHTTP(recorder, httptest.NewRequest("POST", "/ceps", strings.NewReader(string(body))))
+		return recorder
+	}
+
+	oversized := post("01310100", "01310101", "01310102", "01310103", "01310104", "01310105")
+	assert.Equal(t, http.StatusBadRequest, oversized.Code)
+	assert.Equal(t, CodeInvalidRequest, decodeError(t, oversized).Code)
+	assert.Contains(t, oversized.Body.String(), "Máximo de 5 CEPs")
+	assert.Empty(t, oversized.Header().Get("Retry-After"))
+
+	// The empty batch costs its request's token and gives nothing back.
+	empty := post()
+	assert.Equal(t, http.StatusOK, empty.Code)
+	assert.Equal(t, "3", empty.Header().Get("RateLimit-Remaining"))
+
+	charged := post("01310100", "01310101", "01310102")
+	assert.Equal(t, http.StatusOK, charged.Code)
+	assert.Equal(t, "0", charged.Header().Get("RateLimit-Remaining"))
+
+	rejected := post("01310103", "01310104", "01310105")
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, CodeRateLimited, decodeError(t, rejected).Code)
+	assert.NotEmpty(t, rejected.Header().Get("Retry-After"))
+	provider.AssertNumberOfCalls(t, "Lookup", 3)
+
+	// The rejected batch still spent its request's token, leaving none.
+	assert.Equal(t, http.StatusTooManyRequests, post("01310106").Code)
+	provider.AssertNumberOfCalls(t, "Lookup", 3)
+}
@@ -7,23 +7,22 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-type MockCEPGateway struct {
+type MockProvider struct {
 	mock.Mock
+	name string
 }
 
-func (m *MockCEPGateway) GetBrasilAPICEP(ctx context.Context, cep string) (*dto.CEP, error) {
-	// Mock para BrasilAPI
-	args := m.Called(ctx, cep)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*dto.CEP), args.Error(1)
+func NewMockProvider(name string) *MockProvider {
+	return &MockProvider{name: name}
+}
+
+func (m *MockProvider) Name() string {
+	return m.name
 }
 
-func (m *MockCEPGateway) GetViaCEP(ctx context.Context, cep string) (*dto.CEP, error) {
-	// Mock para ViaCEP API
+func (m *MockProvider) Lookup(ctx context.Context, cep string) (*dto.CEP, error) {
 	args := m.Called(ctx, cep)
-	if result := args.Get(0); result == nil {
+	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*dto.CEP), args.Error(1)
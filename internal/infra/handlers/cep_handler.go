@@ -1,106 +1,493 @@
 package handlers
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/AmandaIsrael/faster-cep-api/configs"
 	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/cache"
 	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/logging"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
 	"github.com/AmandaIsrael/faster-cep-api/pkg"
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	errAllProvidersFailed = errors.New("Erro ao obter CEP de todas as APIs")
+	errLookupTimeout      = errors.New("Tempo de espera esgotado para obter o CEP")
 )
 
 type CepHandler struct {
 	ICEPGateway gateway.ICEPGateway
+	cache       cache.Cache
 	config      *configs.Config
+	inflight    singleflight.Group
 }
 
-func NewCepHandler(cepGateway gateway.ICEPGateway, config *configs.Config) *CepHandler {
+func NewCepHandler(cepGateway gateway.ICEPGateway, cepCache cache.Cache, config *configs.Config) *CepHandler {
 	return &CepHandler{
 		ICEPGateway: cepGateway,
+		cache:       cepCache,
 		config:      config,
 	}
 }
 
+// GetCEP answers with the legacy response shape, whose fields depend on the
+// provider that won the race. In merge mode it carries the fields of every
+// provider plus their provenance.
 func (h *CepHandler) GetCEP(w http.ResponseWriter, r *http.Request) {
-	cep := chi.URLParam(r, "cep")
-	if cep == "" {
-		http.Error(w, "CEP é obrigatório", http.StatusBadRequest)
+	h.serveCEP(w, r, func(res *dto.APIResponse, meta *dto.Meta) any {
+		return &dto.CEPResponse{CEP: res.Data, Sources: res.Sources, Meta: meta}
+	})
+}
+
+// GetCEPV2 answers with the canonical dto.Address schema.
+func (h *CepHandler) GetCEPV2(w http.ResponseWriter, r *http.Request) {
+	h.serveCEP(w, r, func(res *dto.APIResponse, meta *dto.Meta) any {
+		address := dto.NewAddress(res.Data, res.Api)
+		if res.Sources != nil {
+			address = dto.NewMergedAddress(res)
+		}
+		address.Meta = meta
+		return address
+	})
+}
+
+// serveCEP looks up the CEP in the URL and answers it through render, with
+// the answering provider in X-CEP-Provider and the time spent on the cache
+// and on each provider in Server-Timing. With ?meta=true, render also gets
+// the source, latency and cache status for the body.
+func (h *CepHandler) serveCEP(w http.ResponseWriter, r *http.Request, render func(*dto.APIResponse, *dto.Meta) any) {
+	cep, err := parseCEP(chi.URLParam(r, "cep"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidCEP, err)
 		return
 	}
 
-	if !pkg.IsValidCEP(cep) {
-		http.Error(w, "CEP deve conter exatamente 8 dígitos numéricos", http.StatusBadRequest)
+	timing := &serverTiming{}
+	ctx := withServerTiming(r.Context(), timing)
+	start := time.Now()
+
+	var res *dto.APIResponse
+	var cached bool
+	if mergeRequested(r) {
+		res, err = h.lookupMerged(ctx, cep)
+		w.Header().Set("X-Cache", "BYPASS")
+	} else {
+		res, cached, err = h.lookup(ctx, cep)
+		if cached {
+			w.Header().Set("X-Cache", "HIT")
+		} else {
+			w.Header().Set("X-Cache", "MISS")
+		}
+	}
+	elapsed := time.Since(start)
+	w.Header().Set("Server-Timing", timing.header(elapsed))
+
+	if err != nil {
+		writeLookupError(w, r, err)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeout)
-	defer cancel()
+	if address := dto.NewAddress(res.Data, res.Api); address.UFMismatch {
+		slog.WarnContext(r.Context(), "UF do provedor não corresponde à faixa do CEP", "cep", cep, "provider", res.Api, "uf", address.StateCode)
+		w.Header().Set("X-CEP-UF-Mismatch", "true")
+	}
 
-	chanResult := make(chan *dto.APIResponse, 1)
-	chanError := make(chan error, 2)
+	var meta *dto.Meta
+	if queryFlag(r, "meta") {
+		meta = &dto.Meta{Source: res.Api, LatencyMs: milliseconds(elapsed), Cached: cached}
+	}
 
-	go func() {
-		resp, err := h.ICEPGateway.GetBrasilAPICEP(ctx, cep)
-		if err != nil {
-			chanError <- err
-			return
-		}
-		chanResult <- &dto.APIResponse{Data: resp, Api: "BrasilAPI"}
-	}()
-
-	go func() {
-		resp, err := h.ICEPGateway.GetViaCEP(ctx, cep)
-		if err != nil {
-			chanError <- err
-			return
+	w.Header().Set("X-CEP-Provider", res.Api)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(render(res, meta))
+}
+
+// parseCEP normalizes a CEP typed by the client and rejects values outside
+// the Correios ranges before any provider is called.
+func parseCEP(raw string) (string, error) {
+	cep, err := pkg.NormalizeCEP(raw)
+	if err != nil {
+		return "", err
+	}
+	if err := pkg.ValidateCEPRange(cep); err != nil {
+		return "", err
+	}
+	return cep, nil
+}
+
+// lookup answers from the cache when possible and otherwise races the
+// providers. Concurrent misses for the same CEP share a single race.
+func (h *CepHandler) lookup(ctx context.Context, cep string) (*dto.APIResponse, bool, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "cep.lookup", trace.WithAttributes(tracing.CEP(cep)))
+	defer span.End()
+	ctx = logging.With(ctx, slog.String("cep", cep))
+
+	start := time.Now()
+	entry, ok := h.cache.Get(ctx, cep)
+	cacheTiming := timingEntry{name: "cache", duration: time.Since(start), desc: "miss"}
+	if ok {
+		cacheTiming.desc = "hit"
+	}
+	serverTimingFrom(ctx).add(cacheTiming)
+
+	if ok {
+		span.SetAttributes(attribute.Bool("cep.cache.hit", true))
+		metrics.LookupDuration.WithLabelValues("hit").Observe(time.Since(start).Seconds())
+		if entry.NotFound {
+			slog.InfoContext(ctx, "consulta de CEP", "cached", true, "outcome", "not_found", "duration", time.Since(start))
+			return nil, true, gateway.ErrCEPNotFound
 		}
-		chanResult <- &dto.APIResponse{Data: resp, Api: "ViaCEP"}
-	}()
+		slog.InfoContext(ctx, "consulta de CEP", "cached", true, "outcome", gateway.OutcomeOK, "provider", entry.Api, "duration", time.Since(start))
+		return &dto.APIResponse{Data: entry.CEP, Api: entry.Api}, true, nil
+	}
+
+	span.SetAttributes(attribute.Bool("cep.cache.hit", false))
+	res, coalesced, err := h.coalescedRace(ctx, cep)
+	elapsed := time.Since(start)
+	metrics.LookupDuration.WithLabelValues("miss").Observe(elapsed.Seconds())
+	span.SetAttributes(attribute.Bool("cep.coalesced", coalesced))
+
+	switch {
+	case err == nil:
+		span.SetAttributes(attribute.String("cep.provider", res.Api))
+		slog.InfoContext(ctx, "consulta de CEP", "cached", false, "coalesced", coalesced, "outcome", gateway.OutcomeOK, "provider", res.Api, "duration", elapsed)
+	case errors.Is(err, gateway.ErrCEPNotFound):
+		slog.InfoContext(ctx, "consulta de CEP", "cached", false, "coalesced", coalesced, "outcome", "not_found", "duration", elapsed)
+	case errors.Is(err, errAllProvidersFailed):
+		span.SetStatus(codes.Error, err.Error())
+		slog.WarnContext(ctx, "consulta de CEP", "cached", false, "coalesced", coalesced, "outcome", "all_failed", "duration", elapsed)
+	default:
+		span.SetStatus(codes.Error, err.Error())
+		slog.WarnContext(ctx, "consulta de CEP", "cached", false, "coalesced", coalesced, "outcome", "timeout", "duration", elapsed)
+	}
+	return res, false, err
+}
+
+// sharedRace is the outcome of a race shared by coalesced requests, with the
+// provider timings each of them reports.
+type sharedRace struct {
+	res     *dto.APIResponse
+	timings []timingEntry
+}
+
+// coalescedRace joins the race in flight for cep, or starts one. The shared
+// race ignores the cancellation of whichever request started it, since
+// others may be waiting on it; each caller still stops waiting when its own
+// context ends.
+func (h *CepHandler) coalescedRace(ctx context.Context, cep string) (*dto.APIResponse, bool, error) {
+	leader := false
+	ch := h.inflight.DoChan(cep, func() (any, error) {
+		leader = true
+		timing := &serverTiming{}
+		res, err := h.raceAndStore(withServerTiming(context.WithoutCancel(ctx), timing), cep)
+		return &sharedRace{res: res, timings: timing.snapshot()}, err
+	})
 
 	select {
-	case res := <-chanResult:
-		h.logCEPResult(res.Data, res.Api)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(res.Data)
+	case result := <-ch:
+		if !leader {
+			metrics.CoalescedLookups.Inc()
+		}
+		shared := result.Val.(*sharedRace)
+		serverTimingFrom(ctx).add(shared.timings...)
+		return shared.res, !leader, result.Err
 	case <-ctx.Done():
-		if len(chanError) == 2 {
-			http.Error(w, "Erro ao obter CEP de todas as APIs", http.StatusInternalServerError)
-		} else {
-			http.Error(w, "Tempo de espera esgotado para obter o CEP", http.StatusGatewayTimeout)
+		return nil, false, errLookupTimeout
+	}
+}
+
+// raceAndStore runs one race and records its outcome once, however many
+// requests share it: race metrics, plus caching of resolved addresses and
+// unanimous "not found".
+func (h *CepHandler) raceAndStore(ctx context.Context, cep string) (*dto.APIResponse, error) {
+	res, err := h.race(ctx, cep)
+
+	switch {
+	case err == nil:
+		metrics.RaceWins.WithLabelValues(res.Api).Inc()
+		h.cache.Set(ctx, cep, &cache.Entry{CEP: res.Data, Api: res.Api})
+	case errors.Is(err, gateway.ErrCEPNotFound):
+		metrics.RaceFailures.WithLabelValues("not_found").Inc()
+		h.cache.Set(ctx, cep, &cache.Entry{NotFound: true})
+	case errors.Is(err, errAllProvidersFailed):
+		metrics.RaceFailures.WithLabelValues("all_failed").Inc()
+	default:
+		metrics.RaceFailures.WithLabelValues("timeout").Inc()
+	}
+	return res, err
+}
+
+// Lookup strategies, selected through config.LookupStrategy. Providers are
+// tried in the order of config.LookupOrder, then in registration order, so
+// the first of them is the preferred.
+const (
+	// StrategyRace calls every available provider at once.
+	StrategyRace = "race"
+	// StrategyHedge calls the preferred provider and each next one only
+	// when the previous has been running for longer than its p95 latency.
+	StrategyHedge = "hedge"
+	// StrategyFailover calls the next provider only after the previous one
+	// failed.
+	StrategyFailover = "failover"
+)
+
+// CheckConfig rejects a config.LookupStrategy other than the known
+// strategies, and a config.LookupOrder or config.MergePreferredProvider
+// naming a provider that is not registered, so a typo fails at startup
+// instead of silently changing how providers are called.
+func (h *CepHandler) CheckConfig() error {
+	switch h.config.LookupStrategy {
+	case "", StrategyRace, StrategyHedge, StrategyFailover:
+	default:
+		return fmt.Errorf("LOOKUP_STRATEGY inválida: %q (use %s, %s ou %s)", h.config.LookupStrategy, StrategyRace, StrategyHedge, StrategyFailover)
+	}
+
+	for _, name := range h.config.LookupOrder {
+		if !slices.ContainsFunc(h.ICEPGateway.Providers(), func(p gateway.Provider) bool { return p.Name() == name }) {
+			return fmt.Errorf("LOOKUP_ORDER cita provedor desconhecido: %q", name)
 		}
 	}
+
+	if name := h.config.MergePreferredProvider; name != "" {
+		if !slices.ContainsFunc(h.ICEPGateway.Providers(), func(p gateway.Provider) bool { return p.Name() == name }) {
+			return fmt.Errorf("MERGE_PREFERRED_PROVIDER cita provedor desconhecido: %q", name)
+		}
+	}
+	return nil
 }
 
-func (h *CepHandler) logCEPResult(cep *dto.CEP, apiName string) {
-	fmt.Printf("=== RESULTADO DA CONSULTA CEP ===\n")
-	fmt.Printf("API Utilizada: %s\n", apiName)
-
-	fields := map[string]string{
-		"CEP":         cep.Cep,
-		"Logradouro":  cep.Logradouro,
-		"Complemento": cep.Complemento,
-		"Unidade":     cep.Unidade,
-		"Bairro":      cep.Bairro,
-		"Rua":         cep.Rua,
-		"Localidade":  cep.Localidade,
-		"UF":          cep.Uf,
-		"Cidade":      cep.Cidade,
-		"Estado":      cep.Estado,
-		"Região":      cep.Regiao,
-		"IBGE":        cep.Ibge,
-		"GIA":         cep.Gia,
-		"DDD":         cep.Ddd,
-		"SIAFI":       cep.Siafi,
-		"Serviço":     cep.Servico,
-	}
-
-	for label, value := range fields {
-		if value != "" {
-			fmt.Printf("%s: %s\n", label, value)
+// ordered sorts providers in place by config.LookupOrder; the providers it
+// does not name follow in registration order.
+func (h *CepHandler) ordered(providers []gateway.Provider) []gateway.Provider {
+	order := h.config.LookupOrder
+	rank := func(p gateway.Provider) int {
+		if i := slices.Index(order, p.Name()); i >= 0 {
+			return i
 		}
+		return len(order)
+	}
+	slices.SortStableFunc(providers, func(a, b gateway.Provider) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+	return providers
+}
+
+type providerResult struct {
+	provider string
+	resp     *dto.CEP
+	err      error
+}
+
+// lookupProvider calls provider, turning an empty payload into "not found",
+// and records how long it took for the Server-Timing header.
+func lookupProvider(ctx context.Context, provider gateway.Provider, cep string) providerResult {
+	start := time.Now()
+	resp, err := provider.Lookup(ctx, cep)
+	if err == nil && resp.IsEmpty() {
+		err = gateway.ErrCEPNotFound
+	}
+
+	outcome := gateway.OutcomeOK
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		outcome = gateway.OutcomeCancelled
+	case err != nil:
+		outcome = gateway.ErrorType(err)
+	}
+	serverTimingFrom(ctx).add(timingEntry{name: provider.Name(), duration: time.Since(start), desc: outcome})
+
+	return providerResult{provider: provider.Name(), resp: resp, err: err}
+}
+
+// queryAll calls every available provider at once and returns their results
+// in registration order once all have answered or config.Timeout expires,
+// followed by the providers left out of the race. Providers still running at
+// the deadline are reported with context.DeadlineExceeded, and timedOut is
+// set.
+func (h *CepHandler) queryAll(ctx context.Context, cep string) (results []providerResult, timedOut bool) {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	providers, skipped := h.ICEPGateway.Available()
+	results = make([]providerResult, len(providers), len(providers)+len(skipped))
+	for i, provider := range providers {
+		results[i] = providerResult{provider: provider.Name(), err: context.DeadlineExceeded}
+	}
+	results = append(results, skippedResults(skipped)...)
+
+	type indexedResult struct {
+		i   int
+		res providerResult
+	}
+	answers := make(chan indexedResult, len(providers))
+	for i, provider := range providers {
+		go func() {
+			answers <- indexedResult{i: i, res: lookupProvider(ctx, provider, cep)}
+		}()
+	}
+
+	for range providers {
+		select {
+		case answer := <-answers:
+			results[answer.i] = answer.res
+		case <-ctx.Done():
+			return results, true
+		}
+	}
+	return results, false
+}
+
+// skippedResults reports the providers left out of the race as failed
+// results, so they are listed with the failures of the others.
+func skippedResults(skipped []*gateway.UnavailableError) []providerResult {
+	results := make([]providerResult, len(skipped))
+	for i, unavailable := range skipped {
+		results[i] = providerResult{provider: unavailable.Provider, err: unavailable}
+	}
+	return results
+}
+
+// noAnswerError is how a lookup fails when none of results succeeded:
+// errLookupTimeout if the deadline cut it short, "not found" if every
+// provider said so, and the failure of each provider otherwise, flagged as
+// unavailable when none of them could be called.
+func noAnswerError(results []providerResult, timedOut bool) error {
+	if timedOut {
+		return errLookupTimeout
+	}
+
+	failed := &providersFailedError{failures: make([]dto.ProviderFailure, 0, len(results))}
+	notFound := len(results) > 0
+	failed.unavailable = len(results) > 0
+	for _, res := range results {
+		if !errors.Is(res.err, gateway.ErrCEPNotFound) {
+			notFound = false
+		}
+		var unavailable *gateway.UnavailableError
+		if !errors.As(res.err, &unavailable) {
+			failed.unavailable = false
+		} else if len(failed.failures) == 0 || unavailable.RetryAfter < failed.retryAfter {
+			failed.retryAfter = unavailable.RetryAfter
+		}
+		failed.failures = append(failed.failures, res.failure())
+	}
+	if notFound {
+		return gateway.ErrCEPNotFound
+	}
+	return failed
+}
+
+func (r providerResult) failure() dto.ProviderFailure {
+	return dto.ProviderFailure{
+		Provider: r.provider,
+		Type:     gateway.ErrorType(r.err),
+		Error:    r.err.Error(),
+	}
+}
+
+// race returns the first usable answer, calling the providers as the
+// configured strategy dictates; a failure always brings the next provider in
+// right away. It gives up as soon as every provider has failed, or with
+// errLookupTimeout when config.Timeout expires first. Providers out of the
+// race are listed among the failures; when that leaves none to call, it
+// fails as unavailable.
+func (h *CepHandler) race(ctx context.Context, cep string) (*dto.APIResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	providers, skipped := h.ICEPGateway.Available()
+	if len(providers) == 0 {
+		return nil, noAnswerError(skippedResults(skipped), false)
+	}
+	providers = h.ordered(providers)
+	results := make(chan providerResult, len(providers))
+
+	launched := 0
+	var hedge <-chan time.Time
+	launchNext := func() {
+		provider := providers[launched]
+		launched++
+		go func() {
+			results <- lookupProvider(ctx, provider, cep)
+		}()
+
+		hedge = nil
+		if h.config.LookupStrategy == StrategyHedge && launched < len(providers) {
+			hedge = time.After(h.hedgeDelay(provider.Name()))
+		}
+	}
+
+	switch h.config.LookupStrategy {
+	case StrategyHedge, StrategyFailover:
+		launchNext()
+	default:
+		for range providers {
+			launchNext()
+		}
+	}
+
+	failures := make([]dto.ProviderFailure, 0, len(providers)+len(skipped))
+	for _, res := range skippedResults(skipped) {
+		failures = append(failures, res.failure())
+	}
+	// A provider out of the race might know the CEP, so "not found" is
+	// only trusted when every provider was called and said so.
+	notFound := len(skipped) == 0
+	// Count results, not passes: a hedge firing is not an answer, and the
+	// race only fails once every provider has been called and has failed.
+	for received := 0; received < len(providers); {
+		select {
+		case res := <-results:
+			received++
+			if res.err == nil {
+				return &dto.APIResponse{Data: res.resp, Api: res.provider}, nil
+			}
+			if !errors.Is(res.err, gateway.ErrCEPNotFound) {
+				notFound = false
+			}
+			failures = append(failures, res.failure())
+			if launched < len(providers) {
+				launchNext()
+			}
+		case <-hedge:
+			slog.DebugContext(ctx, "acionando provedor de reserva", "provider", providers[launched].Name())
+			trace.SpanFromContext(ctx).AddEvent("hedge", trace.WithAttributes(attribute.String("cep.provider", providers[launched].Name())))
+			launchNext()
+		case <-ctx.Done():
+			return nil, errLookupTimeout
+		}
+	}
+
+	if notFound {
+		return nil, gateway.ErrCEPNotFound
+	}
+	return nil, &providersFailedError{failures: failures}
+}
+
+// hedgeDelay is how long the hedge strategy waits on provider before calling
+// the next one: its p95 latency over the recent calls, or config.HedgeDelay
+// while there are no calls to go by. Calls the hedge cut short count for as
+// long as they ran, so a slow provider does not look fast just because its
+// slow calls keep losing.
+func (h *CepHandler) hedgeDelay(provider string) time.Duration {
+	if p95, ok := h.ICEPGateway.LatencyP95(provider); ok {
+		return p95
 	}
+	return h.config.HedgeDelay
 }
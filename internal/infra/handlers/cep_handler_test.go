@@ -1,27 +1,48 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/AmandaIsrael/faster-cep-api/configs"
 	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/cache"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/logging"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
-func setupHandler(mockGateway *MockCEPGateway) *CepHandler {
+func setupHandler(providers ...gateway.Provider) *CepHandler {
 	config := &configs.Config{
-		Timeout: time.Second * 5,
+		Timeout:          time.Second * 5,
+		BatchMaxSize:     100,
+		BatchConcurrency: 4,
 	}
-	return NewCepHandler(mockGateway, config)
+	return NewCepHandler(gateway.NewRegistry(providers...), cache.NewNoop(), config)
+}
+
+func setupProviders() (*MockProvider, *MockProvider) {
+	return NewMockProvider(gateway.BrasilAPIName), NewMockProvider(gateway.ViaCEPName)
 }
 
 func createRequest(method, url, cep string) *http.Request {
@@ -35,8 +56,8 @@ func createRequest(method, url, cep string) *http.Request {
 }
 
 func TestCepHandlerGetCEPSuccessBrasilAPI(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
 
 	expectedCEP := &dto.CEP{
 		Cep:     "01310-100",
@@ -47,8 +68,8 @@ func TestCepHandlerGetCEPSuccessBrasilAPI(t *testing.T) {
 		Servico: "correios",
 	}
 
-	mockGateway.On("GetBrasilAPICEP", mock.Anything, "01310100").Return(expectedCEP, nil)
-	mockGateway.On("GetViaCEP", mock.Anything, "01310100").Return(&dto.CEP{}, errors.New("timeout"))
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(expectedCEP, nil)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{}, errors.New("timeout"))
 
 	req := createRequest("GET", "/cep/01310100", "01310100")
 	recorder := httptest.NewRecorder()
@@ -65,12 +86,12 @@ func TestCepHandlerGetCEPSuccessBrasilAPI(t *testing.T) {
 	assert.Equal(t, expectedCEP.Cidade, response.Cidade)
 	assert.Equal(t, expectedCEP.Bairro, response.Bairro)
 
-	mockGateway.AssertExpectations(t)
+	brasilAPI.AssertExpectations(t)
 }
 
 func TestCepHandlerGetCEPSuccessViaCEP(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
 
 	expectedCEP := &dto.CEP{
 		Cep:        "01310-100",
@@ -82,8 +103,8 @@ func TestCepHandlerGetCEPSuccessViaCEP(t *testing.T) {
 		Ddd:        "11",
 	}
 
-	mockGateway.On("GetViaCEP", mock.Anything, "01310100").Return(expectedCEP, nil)
-	mockGateway.On("GetBrasilAPICEP", mock.Anything, "01310100").Return(&dto.CEP{}, errors.New("timeout"))
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(expectedCEP, nil)
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{}, errors.New("timeout"))
 
 	req := createRequest("GET", "/cep/01310100", "01310100")
 	recorder := httptest.NewRecorder()
@@ -99,12 +120,12 @@ func TestCepHandlerGetCEPSuccessViaCEP(t *testing.T) {
 	assert.Equal(t, expectedCEP.Logradouro, response.Logradouro)
 	assert.Equal(t, expectedCEP.Localidade, response.Localidade)
 
-	mockGateway.AssertExpectations(t)
+	viaCEP.AssertExpectations(t)
 }
 
 func TestCepHandlerGetCEPEmptyCEP(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
 
 	req := createRequest("GET", "/cep/", "")
 	recorder := httptest.NewRecorder()
@@ -114,64 +135,85 @@ func TestCepHandlerGetCEPEmptyCEP(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "CEP é obrigatório")
 
-	mockGateway.AssertNotCalled(t, "GetBrasilAPICEP")
-	mockGateway.AssertNotCalled(t, "GetViaCEP")
+	brasilAPI.AssertNotCalled(t, "Lookup")
+	viaCEP.AssertNotCalled(t, "Lookup")
 }
 
 func TestCepHandlerGetCEPInvalidCEP(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
-
-	invalidCEPs := []string{"123", "12345678a", "123456789", "abcdefgh"}
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	invalidCEPs := map[string]string{
+		"123":       "CEP deve conter exatamente 8 dígitos numéricos",
+		"123456789": "CEP deve conter exatamente 8 dígitos numéricos",
+		"12345678a": "CEP deve conter apenas dígitos, hífen, ponto ou espaço",
+		"abcdefgh":  "CEP deve conter apenas dígitos, hífen, ponto ou espaço",
+		"00000000":  "CEP não pertence a nenhuma faixa de CEP dos Correios",
+		"00999-999": "CEP não pertence a nenhuma faixa de CEP dos Correios",
+	}
 
-	for _, invalidCEP := range invalidCEPs {
+	for invalidCEP, message := range invalidCEPs {
 		t.Run(fmt.Sprintf("CEP_Invalid_%s", invalidCEP), func(t *testing.T) {
-			req := createRequest("GET", "/cep/"+invalidCEP, invalidCEP)
+			req := createRequest("GET", "/cep/"+url.PathEscape(invalidCEP), invalidCEP)
 			recorder := httptest.NewRecorder()
 
 			handler.GetCEP(recorder, req)
 
 			assert.Equal(t, http.StatusBadRequest, recorder.Code)
-			assert.Contains(t, recorder.Body.String(), "CEP deve conter exatamente 8 dígitos numéricos")
+			apiErr := decodeError(t, recorder)
+			assert.Equal(t, CodeInvalidCEP, apiErr.Code)
+			assert.Contains(t, apiErr.Message, message)
 		})
 	}
 
-	mockGateway.AssertNotCalled(t, "GetBrasilAPICEP")
-	mockGateway.AssertNotCalled(t, "GetViaCEP")
+	brasilAPI.AssertNotCalled(t, "Lookup")
+	viaCEP.AssertNotCalled(t, "Lookup")
 }
 
 func TestCepHandlerGetCEPBothAPIsError(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
 
-	mockGateway.On("GetBrasilAPICEP", mock.Anything, "01310100").Return(nil, errors.New("API error"))
-	mockGateway.On("GetViaCEP", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, &gateway.StatusError{StatusCode: 500})
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, gateway.ErrCEPNotFound)
 
 	req := createRequest("GET", "/cep/01310100", "01310100")
 	recorder := httptest.NewRecorder()
 
+	start := time.Now()
 	handler.GetCEP(recorder, req)
 
-	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
-	assert.Contains(t, recorder.Body.String(), "Erro ao obter CEP de todas as APIs")
+	assert.Less(t, time.Since(start), time.Second, "must not wait for the race timeout")
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
 
-	mockGateway.AssertExpectations(t)
+	var response dto.ErrorResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, CodeAllProvidersFailed, response.Error.Code)
+	assert.Equal(t, "Erro ao obter CEP de todas as APIs", response.Error.Message)
+	assert.ElementsMatch(t, []dto.ProviderFailure{
+		{Provider: gateway.BrasilAPIName, Type: gateway.ErrorTypeStatus, Error: "API retornou status 500"},
+		{Provider: gateway.ViaCEPName, Type: gateway.ErrorTypeNotFound, Error: "CEP não encontrado"},
+	}, response.Error.Providers)
+
+	brasilAPI.AssertExpectations(t)
+	viaCEP.AssertExpectations(t)
 }
 
 func TestCepHandlerGetCEPTimeout(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
+	brasilAPI, viaCEP := setupProviders()
 
 	config := &configs.Config{
 		Timeout: time.Millisecond * 1,
 	}
 
-	handler := NewCepHandler(mockGateway, config)
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewNoop(), config)
 
-	mockGateway.On("GetBrasilAPICEP", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
 		time.Sleep(time.Millisecond * 10)
 	}).Return(nil, context.DeadlineExceeded)
 
-	mockGateway.On("GetViaCEP", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+	viaCEP.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
 		time.Sleep(time.Millisecond * 10)
 	}).Return(nil, context.DeadlineExceeded)
 
@@ -181,15 +223,17 @@ func TestCepHandlerGetCEPTimeout(t *testing.T) {
 	handler.GetCEP(recorder, req)
 
 	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
-	assert.Contains(t, recorder.Body.String(), "Tempo de espera esgotado para obter o CEP")
+	apiErr := decodeError(t, recorder)
+	assert.Equal(t, CodeUpstreamTimeout, apiErr.Code)
+	assert.Equal(t, "Tempo de espera esgotado para obter o CEP", apiErr.Message)
 }
 
 func TestCepHandlerGetCEPOneAPIErrorOneTimeout(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
 
-	mockGateway.On("GetBrasilAPICEP", mock.Anything, "01310100").Return(nil, errors.New("API error"))
-	mockGateway.On("GetViaCEP", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+	viaCEP.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
 		time.Sleep(time.Second * 10)
 	}).Return(nil, errors.New("timeout"))
 
@@ -201,35 +245,39 @@ func TestCepHandlerGetCEPOneAPIErrorOneTimeout(t *testing.T) {
 	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
 	assert.Contains(t, recorder.Body.String(), "Tempo de espera esgotado para obter o CEP")
 
-	mockGateway.AssertExpectations(t)
+	brasilAPI.AssertExpectations(t)
+	viaCEP.AssertExpectations(t)
 }
 
 func TestCepHandlerNewCepHandler(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
+	registry := gateway.NewRegistry(setupProviders())
 	config := &configs.Config{Timeout: time.Second}
 
-	handler := NewCepHandler(mockGateway, config)
+	cepCache := cache.NewNoop()
+
+	handler := NewCepHandler(registry, cepCache, config)
 
 	assert.NotNil(t, handler)
-	assert.Equal(t, mockGateway, handler.ICEPGateway)
+	assert.Equal(t, registry, handler.ICEPGateway)
+	assert.Equal(t, cepCache, handler.cache)
 	assert.Equal(t, config, handler.config)
 }
 
 func TestCepHandlerGetCEPValidCEPFormats(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
 
 	expectedCEP := &dto.CEP{
 		Cep:    "01310100",
 		Cidade: "São Paulo",
 	}
 
-	validCEPs := []string{"01310100", "12345678", "00000000", "99999999"}
+	validCEPs := []string{"01310100", "12345678", "69900000", "99999999"}
 
 	for _, cep := range validCEPs {
 		t.Run(fmt.Sprintf("CEP_Valid_%s", cep), func(t *testing.T) {
-			mockGateway.On("GetBrasilAPICEP", mock.Anything, cep).Return(expectedCEP, nil).Once()
-			mockGateway.On("GetViaCEP", mock.Anything, cep).Return(nil, errors.New("error")).Once()
+			brasilAPI.On("Lookup", mock.Anything, cep).Return(expectedCEP, nil).Once()
+			viaCEP.On("Lookup", mock.Anything, cep).Return(nil, errors.New("error")).Once()
 
 			req := createRequest("GET", "/cep/"+cep, cep)
 			recorder := httptest.NewRecorder()
@@ -240,27 +288,760 @@ func TestCepHandlerGetCEPValidCEPFormats(t *testing.T) {
 		})
 	}
 
-	mockGateway.AssertExpectations(t)
+	brasilAPI.AssertExpectations(t)
 }
 
-func TestCepHandlerGetCEPCepWithHyphenShouldFail(t *testing.T) {
-	mockGateway := new(MockCEPGateway)
-	handler := setupHandler(mockGateway)
+func TestCepHandlerGetCEPFormattedCEPs(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
 
-	cepsWithHyphen := []string{"01310-100", "12345-678", "00000-000"}
+	expectedCEP := &dto.CEP{Cep: "01153-000", Rua: "Rua Vitorino Carmilo"}
+	brasilAPI.On("Lookup", mock.Anything, "01153000").Return(expectedCEP, nil)
+	viaCEP.On("Lookup", mock.Anything, "01153000").Return(nil, errors.New("error")).Maybe()
 
-	for _, cep := range cepsWithHyphen {
-		t.Run(fmt.Sprintf("CEP_WithHyphen_%s", cep), func(t *testing.T) {
-			req := createRequest("GET", "/cep/"+cep, cep)
+	formattedCEPs := []string{"01153-000", "01153000", "01.153-000", "01153 000"}
+
+	for _, cep := range formattedCEPs {
+		t.Run(fmt.Sprintf("CEP_Formatted_%s", cep), func(t *testing.T) {
+			req := createRequest("GET", "/cep/"+url.PathEscape(cep), cep)
 			recorder := httptest.NewRecorder()
 
 			handler.GetCEP(recorder, req)
 
-			assert.Equal(t, http.StatusBadRequest, recorder.Code)
-			assert.Contains(t, recorder.Body.String(), "CEP deve conter exatamente 8 dígitos numéricos")
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+
+	brasilAPI.AssertNumberOfCalls(t, "Lookup", len(formattedCEPs))
+	brasilAPI.AssertNotCalled(t, "Lookup", mock.Anything, "01153-000")
+}
+
+func TestCepHandlerGetCEPRacesAllRegisteredProviders(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	openCEP := NewMockProvider("OpenCEP")
+	handler := setupHandler(brasilAPI, viaCEP, openCEP)
+
+	expectedCEP := &dto.CEP{
+		Cep:        "01310-100",
+		Logradouro: "Avenida Paulista",
+	}
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+	openCEP.On("Lookup", mock.Anything, "01310100").Return(expectedCEP, nil)
+
+	req := createRequest("GET", "/cep/01310100", "01310100")
+	recorder := httptest.NewRecorder()
+
+	handler.GetCEP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response dto.CEP
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCEP.Logradouro, response.Logradouro)
+
+	openCEP.AssertExpectations(t)
+}
+
+func TestCepHandlerGetCEPServesFromCache(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Second * 5}
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewMemory(10, time.Minute, time.Minute), config)
+
+	expectedCEP := &dto.CEP{Cep: "01310-100", Rua: "Avenida Paulista"}
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(expectedCEP, nil).Once()
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error")).Maybe()
+
+	first := httptest.NewRecorder()
+	handler.GetCEP(first, createRequest("GET", "/cep/01310100", "01310100"))
+
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+
+	second := httptest.NewRecorder()
+	handler.GetCEP(second, createRequest("GET", "/cep/01310100", "01310100"))
+
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+
+	var response dto.CEP
+	err := json.Unmarshal(second.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCEP.Rua, response.Rua)
+
+	brasilAPI.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func TestCepHandlerGetCEPNegativeCache(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Millisecond * 50}
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewMemory(10, time.Minute, time.Minute), config)
+
+	brasilAPI.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound).Once()
+	viaCEP.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound).Once()
+
+	first := httptest.NewRecorder()
+	handler.GetCEP(first, createRequest("GET", "/cep/99999999", "99999999"))
+
+	assert.Equal(t, http.StatusNotFound, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+
+	second := httptest.NewRecorder()
+	handler.GetCEP(second, createRequest("GET", "/cep/99999999", "99999999"))
+
+	assert.Equal(t, http.StatusNotFound, second.Code)
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+
+	brasilAPI.AssertExpectations(t)
+	viaCEP.AssertExpectations(t)
+}
+
+func TestCepHandlerGetCEPDoesNotCacheUpstreamFailures(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Millisecond * 50}
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewMemory(10, time.Minute, time.Minute), config)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, gateway.ErrCEPNotFound)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		handler.GetCEP(recorder, createRequest("GET", "/cep/01310100", "01310100"))
+
+		assert.Equal(t, http.StatusBadGateway, recorder.Code)
+		assert.Equal(t, "MISS", recorder.Header().Get("X-Cache"))
+	}
+
+	brasilAPI.AssertNumberOfCalls(t, "Lookup", 2)
+}
+
+func TestCepHandlerGetCEPEmptyPayloadDoesNotWin(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	expectedCEP := &dto.CEP{Cep: "01310-100", Rua: "Avenida Paulista"}
+
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{}, nil)
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 20)
+	}).Return(expectedCEP, nil)
+
+	req := createRequest("GET", "/cep/01310100", "01310100")
+	recorder := httptest.NewRecorder()
+
+	handler.GetCEP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response dto.CEP
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCEP.Rua, response.Rua)
+
+	brasilAPI.AssertExpectations(t)
+	viaCEP.AssertExpectations(t)
+}
+
+func TestCepHandlerGetCEPNotFound(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Millisecond * 50}
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewNoop(), config)
+
+	brasilAPI.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+	viaCEP.On("Lookup", mock.Anything, "99999999").Return(&dto.CEP{}, nil)
+
+	req := createRequest("GET", "/cep/99999999", "99999999")
+	recorder := httptest.NewRecorder()
+
+	handler.GetCEP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	apiErr := decodeError(t, recorder)
+	assert.Equal(t, CodeCEPNotFound, apiErr.Code)
+	assert.Equal(t, "CEP não encontrado", apiErr.Message)
+
+	brasilAPI.AssertExpectations(t)
+	viaCEP.AssertExpectations(t)
+}
+
+func TestCepHandlerGetCEPV2NormalizesEachProvider(t *testing.T) {
+	results := map[string]*dto.CEP{
+		gateway.BrasilAPIName: {
+			Cep:    "01310-100",
+			Estado: "SP",
+			Cidade: "São Paulo",
+			Bairro: "Bela Vista",
+			Rua:    "Avenida Paulista",
+		},
+		gateway.ViaCEPName: {
+			Cep:        "01310-100",
+			Logradouro: "Avenida Paulista",
+			Bairro:     "Bela Vista",
+			Localidade: "São Paulo",
+			Uf:         "SP",
+			Estado:     "SP",
+			Regiao:     "Sudeste",
+		},
+	}
+
+	for name, result := range results {
+		t.Run(name, func(t *testing.T) {
+			provider := NewMockProvider(name)
+			handler := setupHandler(provider)
+			provider.On("Lookup", mock.Anything, "01310100").Return(result, nil)
+
+			req := createRequest("GET", "/v2/01310100", "01310100")
+			recorder := httptest.NewRecorder()
+
+			handler.GetCEPV2(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+
+			var response dto.Address
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, dto.Address{
+				Cep:          "01310-100",
+				Street:       "Avenida Paulista",
+				Neighborhood: "Bela Vista",
+				City:         "São Paulo",
+				StateCode:    "SP",
+				State:        "São Paulo",
+				Region:       "Sudeste",
+				Source:       name,
+			}, response)
+		})
+	}
+}
+
+func TestCepHandlerGetCEPFlagsUFMismatch(t *testing.T) {
+	provider := NewMockProvider(gateway.ViaCEPName)
+	handler := setupHandler(provider)
+
+	provider.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100", Uf: "RJ"}, nil)
+
+	req := createRequest("GET", "/v2/01310100", "01310100")
+	recorder := httptest.NewRecorder()
+
+	handler.GetCEPV2(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "true", recorder.Header().Get("X-CEP-UF-Mismatch"))
+
+	var response dto.Address
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.True(t, response.UFMismatch)
+}
+
+func TestCepHandlerGetCEPRecordsMetrics(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error")).Maybe()
+
+	winsBefore := testutil.ToFloat64(metrics.RaceWins.WithLabelValues(gateway.BrasilAPIName))
+
+	handler.GetCEP(httptest.NewRecorder(), createRequest("GET", "/cep/01310100", "01310100"))
+	handler.GetCEP(httptest.NewRecorder(), createRequest("GET", "/cep/abc", "abc"))
+
+	assert.Equal(t, winsBefore+1, testutil.ToFloat64(metrics.RaceWins.WithLabelValues(gateway.BrasilAPIName)))
+}
+
+func TestCepHandlerGetCEPTracesLookup(t *testing.T) {
+	exporter, restore := tracing.InstallInMemory()
+	defer restore()
+
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100", Estado: "SP"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("timeout")).Maybe()
+
+	handler.GetCEP(httptest.NewRecorder(), createRequest("GET", "/01310100", "01310100"))
+
+	spans := exporter.GetSpans()
+	var lookup, winner *tracetest.SpanStub
+	for i := range spans {
+		switch {
+		case spans[i].Name == "cep.lookup":
+			lookup = &spans[i]
+		case spans[i].Name == "provider.lookup" && slices.Contains(spans[i].Attributes, attribute.String("cep.provider", gateway.BrasilAPIName)):
+			winner = &spans[i]
+		}
+	}
+	if assert.NotNil(t, lookup) && assert.NotNil(t, winner) {
+		assert.Contains(t, lookup.Attributes, attribute.String("cep.provider", gateway.BrasilAPIName))
+		assert.Contains(t, lookup.Attributes, attribute.Bool("cep.cache.hit", false))
+		assert.Equal(t, lookup.SpanContext.SpanID(), winner.Parent.SpanID())
+	}
+}
+
+func TestCepHandlerGetCEPLogsLookup(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, &configs.Config{LogLevel: "info", LogFormat: logging.FormatJSON}))
+	defer slog.SetDefault(previous)
+
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, gateway.ErrCEPNotFound)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, gateway.ErrCEPNotFound)
+
+	req := createRequest("GET", "/01310100", "01310100")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-42"))
+	handler.GetCEP(httptest.NewRecorder(), req)
+
+	var providerLines, lookupLines int
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		if record["request_id"] != "req-42" {
+			// Providers still running from earlier tests log here too.
+			continue
+		}
+		assert.Equal(t, "01310100", record["cep"])
+		assert.Equal(t, "not_found", record["outcome"])
+		switch record["msg"] {
+		case "consulta ao provedor":
+			providerLines++
+			assert.Contains(t, []any{gateway.BrasilAPIName, gateway.ViaCEPName}, record["provider"])
+		case "consulta de CEP":
+			lookupLines++
+		}
+	}
+	assert.Equal(t, 2, providerLines)
+	assert.Equal(t, 1, lookupLines)
+}
+
+func TestCepHandlerGetCEPCoalescesConcurrentLookups(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	handler := setupHandler(provider)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	provider.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(&dto.CEP{Cep: "01310-100"}, nil).Once()
+
+	coalescedBefore := testutil.ToFloat64(metrics.CoalescedLookups)
+	winsBefore := testutil.ToFloat64(metrics.RaceWins.WithLabelValues(gateway.BrasilAPIName))
+
+	const requests = 5
+	recorders := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.GetCEP(recorders[i], createRequest("GET", "/01310100", "01310100"))
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+	// Give the followers time to join the race before it finishes.
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+
+	for _, recorder := range recorders {
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "01310-100")
+	}
+	provider.AssertNumberOfCalls(t, "Lookup", 1)
+	assert.Equal(t, coalescedBefore+requests-1, testutil.ToFloat64(metrics.CoalescedLookups))
+	assert.Equal(t, winsBefore+1, testutil.ToFloat64(metrics.RaceWins.WithLabelValues(gateway.BrasilAPIName)))
+}
+
+func TestCepHandlerGetCEPCoalescedRaceSurvivesLeaderCancellation(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	handler := setupHandler(provider)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	provider.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(&dto.CEP{Cep: "01310-100"}, nil).Once()
+
+	leaderReq := createRequest("GET", "/01310100", "01310100")
+	leaderCtx, cancelLeader := context.WithCancel(leaderReq.Context())
+	leader := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.GetCEP(leader, leaderReq.WithContext(leaderCtx))
+	}()
+	<-started
+
+	follower := httptest.NewRecorder()
+	followerDone := make(chan struct{})
+	go func() {
+		defer close(followerDone)
+		handler.GetCEP(follower, createRequest("GET", "/01310100", "01310100"))
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	cancelLeader()
+	<-done
+	close(release)
+	<-followerDone
+
+	assert.Equal(t, http.StatusGatewayTimeout, leader.Code)
+	assert.Equal(t, http.StatusOK, follower.Code)
+	provider.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func setupStrategyHandler(strategy string, providers ...gateway.Provider) *CepHandler {
+	config := &configs.Config{
+		Timeout:        time.Second,
+		LookupStrategy: strategy,
+		HedgeDelay:     time.Millisecond * 30,
+	}
+	return NewCepHandler(gateway.NewRegistry(providers...), cache.NewNoop(), config)
+}
+
+func TestCepHandlerGetCEPHedgeSkipsBackupWhenPreferredIsFast(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyHedge, brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	viaCEP.AssertNotCalled(t, "Lookup", mock.Anything, mock.Anything)
+}
+
+func TestCepHandlerGetCEPHedgeCallsBackupAfterDelay(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyHedge, brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.Canceled)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "01310-100")
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*30)
+	viaCEP.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func TestCepHandlerGetCEPHedgeDelayFollowsP95(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyHedge, brasilAPI, viaCEP)
+	handler.config.HedgeDelay = time.Hour
+
+	assert.Equal(t, time.Hour, handler.hedgeDelay(gateway.BrasilAPIName))
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+	handler.GetCEP(httptest.NewRecorder(), createRequest("GET", "/01310100", "01310100"))
+
+	assert.Less(t, handler.hedgeDelay(gateway.BrasilAPIName), time.Second)
+	assert.Equal(t, time.Hour, handler.hedgeDelay(gateway.ViaCEPName))
+}
+
+func TestCepHandlerGetCEPHedgeDelayCountsCancelledPrimary(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyHedge, brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+	brasilAPI.On("Lookup", mock.Anything, "20040002").Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.Canceled)
+	viaCEP.On("Lookup", mock.Anything, "20040002").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 50)
+	}).Return(&dto.CEP{Cep: "20040-002"}, nil)
+
+	handler.GetCEP(httptest.NewRecorder(), createRequest("GET", "/01310100", "01310100"))
+	fast := handler.hedgeDelay(gateway.BrasilAPIName)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/20040002", "20040002"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// The cancelled call ran for at least the fallback delay plus ViaCEP's
+	// answer, and is recorded once it returns, after the response.
+	assert.Less(t, fast, time.Millisecond*30)
+	assert.Eventually(t, func() bool {
+		return handler.hedgeDelay(gateway.BrasilAPIName) >= time.Millisecond*30
+	}, time.Second, time.Millisecond*5)
+}
+
+func TestCepHandlerGetCEPFollowsLookupOrder(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyFailover, brasilAPI, viaCEP)
+	handler.config.LookupOrder = []string{gateway.ViaCEPName}
+
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, gateway.ViaCEPName, recorder.Header().Get("X-CEP-Provider"))
+	brasilAPI.AssertNotCalled(t, "Lookup", mock.Anything, mock.Anything)
+}
+
+func TestCepHandlerCheckConfig(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyHedge, brasilAPI, viaCEP)
+	assert.NoError(t, handler.CheckConfig())
+
+	handler.config.LookupOrder = []string{gateway.ViaCEPName, gateway.BrasilAPIName}
+	assert.NoError(t, handler.CheckConfig())
+
+	handler.config.LookupOrder = []string{"OpenCEP"}
+	assert.ErrorContains(t, handler.CheckConfig(), "OpenCEP")
+
+	handler.config.LookupOrder = nil
+	handler.config.MergePreferredProvider = gateway.ViaCEPName
+	assert.NoError(t, handler.CheckConfig())
+
+	handler.config.MergePreferredProvider = "ViaCEPP"
+	assert.ErrorContains(t, handler.CheckConfig(), "MERGE_PREFERRED_PROVIDER")
+
+	handler.config.MergePreferredProvider = ""
+	handler.config.LookupStrategy = "fastest"
+	assert.ErrorContains(t, handler.CheckConfig(), "fastest")
+}
+
+func TestCepHandlerGetCEPFailoverCallsNextOnlyAfterFailure(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyFailover, brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 60)
+	}).Return(&dto.CEP{Cep: "01310-100"}, nil)
+	brasilAPI.On("Lookup", mock.Anything, "20040002").Return(nil, errors.New("API error"))
+	viaCEP.On("Lookup", mock.Anything, "20040002").Return(&dto.CEP{Cep: "20040-002"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	viaCEP.AssertNotCalled(t, "Lookup", mock.Anything, "01310100")
+
+	recorder = httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/20040002", "20040002"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "20040-002")
+}
+
+func TestCepHandlerGetCEPFailoverReportsEveryFailure(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyFailover, brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+	viaCEP.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/99999999", "99999999"))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	viaCEP.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func TestCepHandlerGetCEPReportsProviderAndTiming(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+	viaCEP.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 10)
+	}).Return(&dto.CEP{Cep: "01310-100", Uf: "SP"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, gateway.ViaCEPName, recorder.Header().Get("X-CEP-Provider"))
+	serverTiming := recorder.Header().Get("Server-Timing")
+	assert.Contains(t, serverTiming, `cache;dur=`)
+	assert.Contains(t, serverTiming, `BrasilAPI;dur=`)
+	assert.Contains(t, serverTiming, `desc="transport"`)
+	assert.Regexp(t, `ViaCEP;dur=\d+\.\d;desc="ok"`, serverTiming)
+	assert.Contains(t, serverTiming, "total;dur=")
+	assert.NotContains(t, recorder.Body.String(), "meta")
+}
+
+func TestCepHandlerGetCEPMetaOnRequest(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	config := &configs.Config{Timeout: time.Second}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewMemory(10, time.Minute, time.Minute), config)
+
+	provider.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100", Estado: "SP"}, nil).Once()
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100?meta=true", "01310100"))
+
+	var legacy dto.CEPResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &legacy))
+	assert.Equal(t, "01310-100", legacy.Cep)
+	assert.Equal(t, gateway.BrasilAPIName, legacy.Meta.Source)
+	assert.False(t, legacy.Meta.Cached)
+	assert.Greater(t, legacy.Meta.LatencyMs, 0.0)
+
+	recorder = httptest.NewRecorder()
+	handler.GetCEPV2(recorder, createRequest("GET", "/v2/01310100?meta=1", "01310100"))
+
+	var address dto.Address
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &address))
+	assert.Equal(t, gateway.BrasilAPIName, address.Meta.Source)
+	assert.True(t, address.Meta.Cached)
+	assert.Equal(t, gateway.BrasilAPIName, recorder.Header().Get("X-CEP-Provider"))
+	assert.Contains(t, recorder.Header().Get("Server-Timing"), `cache;dur=`)
+	assert.NotContains(t, recorder.Header().Get("Server-Timing"), "BrasilAPI;")
+}
+
+func TestCepHandlerGetCEPCoalescedFollowersReportProviderTiming(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	handler := setupHandler(provider)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	provider.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(&dto.CEP{Cep: "01310-100"}, nil).Once()
+
+	leader := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.GetCEP(leader, createRequest("GET", "/01310100", "01310100"))
+	}()
+	<-started
+
+	follower := httptest.NewRecorder()
+	followerDone := make(chan struct{})
+	go func() {
+		defer close(followerDone)
+		handler.GetCEP(follower, createRequest("GET", "/01310100", "01310100"))
+	}()
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	<-done
+	<-followerDone
+
+	for _, recorder := range []*httptest.ResponseRecorder{leader, follower} {
+		assert.Equal(t, gateway.BrasilAPIName, recorder.Header().Get("X-CEP-Provider"))
+		assert.Equal(t, 1, strings.Count(recorder.Header().Get("Server-Timing"), "BrasilAPI;dur="))
+	}
+	provider.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func TestCepHandlerGetCEPHedgeWaitsForBackupAfterPrimaryFails(t *testing.T) {
+	for name, primaryErr := range map[string]error{
+		"error":     errors.New("API error"),
+		"not found": gateway.ErrCEPNotFound,
+	} {
+		t.Run(name, func(t *testing.T) {
+			brasilAPI, viaCEP := setupProviders()
+			handler := setupStrategyHandler(StrategyHedge, brasilAPI, viaCEP)
+
+			brasilAPI.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+				time.Sleep(time.Millisecond * 60)
+			}).Return(nil, primaryErr)
+			viaCEP.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+				time.Sleep(time.Millisecond * 150)
+			}).Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+			recorder := httptest.NewRecorder()
+			handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, gateway.ViaCEPName, recorder.Header().Get("X-CEP-Provider"))
 		})
 	}
+}
+
+func TestCepHandlerGetCEPReportsSkippedProviders(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyFailover, gateway.WithLimit(brasilAPI, 0.001, 1, 0), viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01153000").Return(nil, errors.New("API error"))
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01153000", "01153000"))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	apiErr := decodeError(t, recorder)
+	assert.Equal(t, CodeAllProvidersFailed, apiErr.Code)
+	assert.ElementsMatch(t, []dto.ProviderFailure{
+		{Provider: gateway.BrasilAPIName, Type: gateway.ErrorTypeRateLimited, Error: gateway.ErrRateLimited.Error()},
+		{Provider: gateway.ViaCEPName, Type: gateway.ErrorTypeTransport, Error: "API error"},
+	}, apiErr.Providers)
+	brasilAPI.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func TestCepHandlerGetCEPUnavailableWhenEveryProviderIsSkipped(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupStrategyHandler(StrategyFailover, gateway.WithLimit(brasilAPI, 0.001, 1, 0), gateway.WithLimit(viaCEP, 0.01, 1, 0))
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.GetCEPV2(recorder, createRequest("GET", "/v2/01153000", "01153000"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "100", recorder.Header().Get("Retry-After"))
+	apiErr := decodeError(t, recorder)
+	assert.Equal(t, CodeUnavailable, apiErr.Code)
+	assert.Len(t, apiErr.Providers, 2)
+	for _, failure := range apiErr.Providers {
+		assert.Equal(t, gateway.ErrorTypeRateLimited, failure.Type)
+	}
+	brasilAPI.AssertNumberOfCalls(t, "Lookup", 1)
+	viaCEP.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func TestCepHandlerGetCEPNotFoundIsNotTrustedWithSkippedProviders(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Second, LookupStrategy: StrategyFailover}
+	cepCache := cache.NewMemory(10, time.Minute, time.Minute)
+	handler := NewCepHandler(gateway.NewRegistry(gateway.WithBreaker(brasilAPI, 1, time.Minute), viaCEP), cepCache, config)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API error"))
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100", "01310100"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
 
-	mockGateway.AssertNotCalled(t, "GetBrasilAPICEP")
-	mockGateway.AssertNotCalled(t, "GetViaCEP")
+	recorder = httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/99999999", "99999999"))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	apiErr := decodeError(t, recorder)
+	assert.ElementsMatch(t, []dto.ProviderFailure{
+		{Provider: gateway.BrasilAPIName, Type: gateway.ErrorTypeCircuitOpen, Error: gateway.ErrCircuitOpen.Error()},
+		{Provider: gateway.ViaCEPName, Type: gateway.ErrorTypeNotFound, Error: gateway.ErrCEPNotFound.Error()},
+	}, apiErr.Providers)
+	_, cached := cepCache.Get(t.Context(), "99999999")
+	assert.False(t, cached)
+	brasilAPI.AssertNumberOfCalls(t, "Lookup", 1)
 }
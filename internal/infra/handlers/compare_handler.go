@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/logging"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CompareCEP queries every available provider for a CEP and answers their
+// results side by side in the v2 schema, with the fields on which they
+// disagree after accent, case and spacing normalization. It fails like
+// GetCEP only when no provider returned an address.
+func (h *CepHandler) CompareCEP(w http.ResponseWriter, r *http.Request) {
+	cep, err := parseCEP(chi.URLParam(r, "cep"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidCEP, err)
+		return
+	}
+
+	ctx, span := tracing.Tracer().Start(r.Context(), "cep.compare", trace.WithAttributes(tracing.CEP(cep)))
+	defer span.End()
+	ctx = logging.With(ctx, slog.String("cep", cep))
+
+	timing := &serverTiming{}
+	start := time.Now()
+	results, timedOut := h.queryAll(withServerTiming(ctx, timing), cep)
+	w.Header().Set("Server-Timing", timing.header(time.Since(start)))
+	providers := make([]*dto.ProviderAddress, 0, len(results))
+	answered := false
+	for _, res := range results {
+		if res.err != nil {
+			failure := res.failure()
+			providers = append(providers, &dto.ProviderAddress{Provider: res.provider, Error: &failure})
+			continue
+		}
+		answered = true
+		providers = append(providers, &dto.ProviderAddress{Provider: res.provider, Data: dto.NewAddress(res.resp, res.provider)})
+	}
+
+	if !answered {
+		writeLookupError(w, r, noAnswerError(results, timedOut))
+		return
+	}
+
+	comparison := dto.NewComparison(cep, providers)
+	span.SetAttributes(attribute.Bool("cep.consensus", comparison.Consensus))
+	if !comparison.Consensus {
+		fields := make([]string, 0, len(comparison.Disagreements))
+		for _, disagreement := range comparison.Disagreements {
+			fields = append(fields, disagreement.Field)
+		}
+		slog.InfoContext(ctx, "provedores divergem", "fields", fields)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
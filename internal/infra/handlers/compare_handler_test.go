@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/cache"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCepHandlerCompareCEPShowsProvidersSideBySide(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01153000").Return(&dto.CEP{
+		Cep:    "01153000",
+		Estado: "SP",
+		Cidade: "SAO PAULO",
+		Bairro: "Campos Elíseos",
+		Rua:    "Rua Vitorino Carmilo",
+	}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01153000").Return(&dto.CEP{
+		Cep:        "01153-000",
+		Logradouro: "Rua Vitorino Camilo",
+		Bairro:     "Campos Eliseos",
+		Localidade: "São Paulo",
+		Uf:         "SP",
+		Ibge:       "3550308",
+	}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.CompareCEP(recorder, createRequest("GET", "/compare/01153-000", "01153-000"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var response dto.Comparison
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "01153-000", response.Cep)
+	assert.Len(t, response.Providers, 2)
+	assert.Equal(t, gateway.BrasilAPIName, response.Providers[0].Provider)
+	assert.Equal(t, "Rua Vitorino Carmilo", response.Providers[0].Data.Street)
+	assert.Equal(t, gateway.ViaCEPName, response.Providers[1].Provider)
+	assert.Equal(t, "3550308", response.Providers[1].Data.Ibge)
+
+	assert.False(t, response.Consensus)
+	assert.Len(t, response.Disagreements, 1)
+	assert.Equal(t, "street", response.Disagreements[0].Field)
+	assert.Equal(t, "Rua Vitorino Camilo", response.Disagreements[0].Values[gateway.ViaCEPName])
+}
+
+func TestCepHandlerCompareCEPListsFailedProviders(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Millisecond * 50}
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewNoop(), config)
+
+	brasilAPI.On("Lookup", mock.Anything, "01153000").Return(&dto.CEP{Cep: "01153000", Estado: "SP"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01153000").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 200)
+	}).Return(&dto.CEP{Cep: "01153-000"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.CompareCEP(recorder, createRequest("GET", "/compare/01153000", "01153000"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response dto.Comparison
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.True(t, response.Consensus)
+	assert.Nil(t, response.Providers[1].Data)
+	assert.Equal(t, gateway.ErrorTypeTimeout, response.Providers[1].Error.Type)
+}
+
+func TestCepHandlerCompareCEPFailures(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+	viaCEP.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+	brasilAPI.On("Lookup", mock.Anything, "20040002").Return(nil, errors.New("API error"))
+	viaCEP.On("Lookup", mock.Anything, "20040002").Return(nil, gateway.ErrCEPNotFound)
+
+	recorder := httptest.NewRecorder()
+	handler.CompareCEP(recorder, createRequest("GET", "/compare/abc", "abc"))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, CodeInvalidCEP, decodeError(t, recorder).Code)
+
+	recorder = httptest.NewRecorder()
+	handler.CompareCEP(recorder, createRequest("GET", "/compare/99999999", "99999999"))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, CodeCEPNotFound, decodeError(t, recorder).Code)
+
+	recorder = httptest.NewRecorder()
+	handler.CompareCEP(recorder, createRequest("GET", "/compare/20040002", "20040002"))
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Len(t, decodeError(t, recorder).Providers, 2)
+}
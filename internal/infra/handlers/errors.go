@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Error codes answered in dto.APIError. They are part of the API contract:
+// clients branch on them, so existing values must never change.
+const (
+	CodeInvalidCEP         = "INVALID_CEP"
+	CodeCEPNotFound        = "CEP_NOT_FOUND"
+	CodeUpstreamTimeout    = "UPSTREAM_TIMEOUT"
+	CodeAllProvidersFailed = "ALL_PROVIDERS_FAILED"
+	CodeInvalidRequest     = "INVALID_REQUEST"
+	CodeRouteNotFound      = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeUnavailable        = "PROVIDERS_UNAVAILABLE"
+	CodeInternalError      = "INTERNAL_ERROR"
+)
+
+var (
+	errRouteNotFound    = errors.New("Rota não encontrada")
+	errMethodNotAllowed = errors.New("Método não permitido para esta rota")
+	errRateLimited      = errors.New("Limite de requisições excedido; tente novamente após o tempo indicado em Retry-After")
+	errItemRateLimited  = errors.New("Limite de requisições excedido; CEP não consultado")
+	errUnavailable      = errors.New("Nenhuma API disponível no momento; tente novamente após o tempo indicado em Retry-After")
+	errInternal         = errors.New("Erro interno; informe o request_id ao relatar o problema")
+)
+
+// providersFailedError is returned by race when every provider answered with
+// an error and at least one of them was not "not found". When unavailable is
+// set, none was called at all: each was out of the race, and retryAfter is
+// when the first of them is expected back.
+type providersFailedError struct {
+	failures    []dto.ProviderFailure
+	unavailable bool
+	retryAfter  time.Duration
+}
+
+func (e *providersFailedError) Error() string {
+	if e.unavailable {
+		return errUnavailable.Error()
+	}
+	return errAllProvidersFailed.Error()
+}
+
+func (e *providersFailedError) Is(target error) bool {
+	return target == errAllProvidersFailed
+}
+
+// writeError answers status with the JSON error envelope, tagged with the
+// request ID so clients can quote it when reporting a problem.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, err error) {
+	apiErr := &dto.APIError{
+		Code:      code,
+		Message:   err.Error(),
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+	var failed *providersFailedError
+	if errors.As(err, &failed) {
+		apiErr.Providers = failed.failures
+	}
+	writeJSON(w, status, &dto.ErrorResponse{Error: apiErr})
+}
+
+// writeLookupError answers a lookup that got no address: 404 when the CEP
+// does not exist, 503 with Retry-After when no provider could be called, 502
+// when every provider failed and 504 when the deadline ran out first.
+func writeLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	var failed *providersFailedError
+	switch {
+	case errors.Is(err, gateway.ErrCEPNotFound):
+		writeError(w, r, http.StatusNotFound, CodeCEPNotFound, err)
+	case errors.As(err, &failed) && failed.unavailable:
+		w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(failed.retryAfter.Seconds())), 1)))
+		writeError(w, r, http.StatusServiceUnavailable, CodeUnavailable, err)
+	case errors.Is(err, errAllProvidersFailed):
+		writeError(w, r, http.StatusBadGateway, CodeAllProvidersFailed, err)
+	default:
+		writeError(w, r, http.StatusGatewayTimeout, CodeUpstreamTimeout, err)
+	}
+}
+
+// NotFound answers unknown routes with the JSON error envelope.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, CodeRouteNotFound, errRouteNotFound)
+}
+
+// MethodNotAllowed answers known routes called with the wrong method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, errMethodNotAllowed)
+}
+
+// RateLimited answers requests rejected by the inbound rate limit.
+func RateLimited(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusTooManyRequests, CodeRateLimited, errRateLimited)
+}
+
+// Recoverer turns a panic in next into a logged stack trace and a 500 with
+// the JSON error envelope. http.ErrAbortHandler is re-raised, since it only
+// asks net/http to drop the connection.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			slog.ErrorContext(r.Context(), "panic ao atender a requisição", "panic", rec, "stack", string(debug.Stack()))
+			writeError(w, r, http.StatusInternalServerError, CodeInternalError, errInternal)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
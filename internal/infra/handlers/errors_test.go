@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeError(t *testing.T, recorder *httptest.ResponseRecorder) *dto.APIError {
+	t.Helper()
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var response dto.ErrorResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	if assert.NotNil(t, response.Error) {
+		return response.Error
+	}
+	return &dto.APIError{}
+}
+
+func TestWriteErrorIncludesRequestID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/01310100", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+	recorder := httptest.NewRecorder()
+
+	writeError(recorder, req, http.StatusBadRequest, CodeInvalidCEP, errors.New("CEP é obrigatório"))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"error": {"code": "INVALID_CEP", "message": "CEP é obrigatório", "request_id": "req-1"}}`, recorder.Body.String())
+}
+
+func TestWriteErrorListsProviderFailures(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	err := &providersFailedError{failures: []dto.ProviderFailure{
+		{Provider: "BrasilAPI", Type: "status", Error: "API retornou status 500"},
+	}}
+
+	writeError(recorder, httptest.NewRequest("GET", "/01310100", nil), http.StatusBadGateway, CodeAllProvidersFailed, err)
+
+	apiErr := decodeError(t, recorder)
+	assert.Equal(t, CodeAllProvidersFailed, apiErr.Code)
+	assert.Empty(t, apiErr.RequestID)
+	assert.Equal(t, err.failures, apiErr.Providers)
+}
+
+func TestNotFoundAndMethodNotAllowed(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NotFound(recorder, httptest.NewRequest("GET", "/v2/", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, CodeRouteNotFound, decodeError(t, recorder).Code)
+
+	recorder = httptest.NewRecorder()
+	MethodNotAllowed(recorder, httptest.NewRequest("DELETE", "/01310100", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, CodeMethodNotAllowed, decodeError(t, recorder).Code)
+}
+
+func TestRateLimited(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	RateLimited(recorder, httptest.NewRequest("GET", "/01310100", nil))
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, CodeRateLimited, decodeError(t, recorder).Code)
+}
+
+func TestRecovererAnswersPanicWithEnvelope(t *testing.T) {
+	handler := middleware.RequestID(Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/01310100", nil))
+
+	apiErr := decodeError(t, recorder)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, CodeInternalError, apiErr.Code)
+	assert.NotEmpty(t, apiErr.RequestID)
+}
+
+func TestRecovererReraisesAbortHandler(t *testing.T) {
+	handler := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
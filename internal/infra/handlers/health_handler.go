@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+)
+
+const (
+	healthStatusOK          = "ok"
+	healthStatusReady       = "ready"
+	healthStatusUnavailable = "unavailable"
+)
+
+type HealthHandler struct {
+	ICEPGateway gateway.ICEPGateway
+	config      *configs.Config
+}
+
+func NewHealthHandler(cepGateway gateway.ICEPGateway, config *configs.Config) *HealthHandler {
+	return &HealthHandler{
+		ICEPGateway: cepGateway,
+		config:      config,
+	}
+}
+
+// Liveness answers as long as the process can serve HTTP.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &dto.HealthStatus{Status: healthStatusOK})
+}
+
+// Readiness answers 200 when at least one provider is reachable. Providers
+// that succeeded within config.ReadinessMaxAge are trusted without a new
+// call; the others are probed concurrently.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	providers := h.ICEPGateway.Providers()
+	lastSuccess := make(map[string]time.Time, len(providers))
+	for _, status := range h.ICEPGateway.Status() {
+		if status.LastSuccess != nil {
+			lastSuccess[status.Name] = *status.LastSuccess
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.ReadinessTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := &dto.HealthStatus{Status: healthStatusUnavailable, Providers: make(map[string]string, len(providers))}
+
+	for _, provider := range providers {
+		if time.Since(lastSuccess[provider.Name()]) < h.config.ReadinessMaxAge {
+			result.Providers[provider.Name()] = healthStatusOK
+			result.Status = healthStatusReady
+			continue
+		}
+
+		wg.Add(1)
+		go func(provider gateway.Provider) {
+			defer wg.Done()
+			err := gateway.Probe(ctx, provider)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Providers[provider.Name()] = err.Error()
+				return
+			}
+			result.Providers[provider.Name()] = healthStatusOK
+			result.Status = healthStatusReady
+		}(provider)
+	}
+	wg.Wait()
+
+	if result.Status != healthStatusReady {
+		writeJSON(w, http.StatusServiceUnavailable, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ProviderStatus reports each provider's recent success rate and latency.
+func (h *HealthHandler) ProviderStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &dto.ProvidersStatusResponse{Providers: h.ICEPGateway.Status()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupHealthHandler(providers ...gateway.Provider) (*HealthHandler, *gateway.Registry) {
+	registry := gateway.NewRegistry(providers...)
+	config := &configs.Config{
+		ReadinessTimeout: time.Millisecond * 100,
+		ReadinessMaxAge:  time.Minute,
+	}
+	return NewHealthHandler(registry, config), registry
+}
+
+func TestHealthHandlerLiveness(t *testing.T) {
+	handler, _ := setupHealthHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.Liveness(recorder, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, recorder.Body.String())
+}
+
+func TestHealthHandlerReadinessWithOneReachableProvider(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler, _ := setupHealthHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+	viaCEP.On("Lookup", mock.Anything, mock.Anything).Return(&dto.CEP{Cep: "01001-000"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.Readiness(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response dto.HealthStatus
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, healthStatusReady, response.Status)
+	assert.Equal(t, healthStatusOK, response.Providers[gateway.ViaCEPName])
+	assert.Equal(t, "connection refused", response.Providers[gateway.BrasilAPIName])
+}
+
+func TestHealthHandlerReadinessWithNoReachableProvider(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler, _ := setupHealthHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+	viaCEP.On("Lookup", mock.Anything, mock.Anything).Return(nil, errors.New("API retornou status 503"))
+
+	recorder := httptest.NewRecorder()
+	handler.Readiness(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), healthStatusUnavailable)
+}
+
+func TestHealthHandlerReadinessTrustsRecentSuccess(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	handler, registry := setupHealthHandler(provider)
+
+	provider.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil).Once()
+	registry.Providers()[0].Lookup(t.Context(), "01310100")
+
+	recorder := httptest.NewRecorder()
+	handler.Readiness(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	provider.AssertNumberOfCalls(t, "Lookup", 1)
+}
+
+func TestHealthHandlerProviderStatus(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler, registry := setupHealthHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Return(nil, errors.New("API retornou status 500"))
+	for _, provider := range registry.Providers() {
+		provider.Lookup(t.Context(), "01310100")
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ProviderStatus(recorder, httptest.NewRequest("GET", "/status/providers", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response dto.ProvidersStatusResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Providers, 2)
+	assert.Equal(t, gateway.BrasilAPIName, response.Providers[0].Name)
+	assert.Equal(t, 1.0, response.Providers[0].SuccessRate)
+	assert.Equal(t, gateway.ViaCEPName, response.Providers[1].Name)
+	assert.Equal(t, 0.0, response.Providers[1].SuccessRate)
+	assert.Equal(t, "API retornou status 500", response.Providers[1].LastError)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/logging"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mergeRequested reports whether the client opted into merge mode, through
+// ?merge=true or the X-CEP-Merge: true header.
+func mergeRequested(r *http.Request) bool {
+	merge, err := strconv.ParseBool(r.Header.Get("X-CEP-Merge"))
+	return queryFlag(r, "merge") || (err == nil && merge)
+}
+
+// queryFlag reports whether the query parameter name is set to a true value
+// such as "true" or "1".
+func queryFlag(r *http.Request, name string) bool {
+	value, err := strconv.ParseBool(r.URL.Query().Get(name))
+	return err == nil && value
+}
+
+// lookupMerged waits for every available provider, up to config.Timeout, and
+// combines their answers with dto.MergeCEPs. The cache is bypassed, as it
+// only holds single-provider results.
+func (h *CepHandler) lookupMerged(ctx context.Context, cep string) (*dto.APIResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "cep.lookup", trace.WithAttributes(tracing.CEP(cep), attribute.Bool("cep.merge", true)))
+	defer span.End()
+	ctx = logging.With(ctx, slog.String("cep", cep))
+
+	start := time.Now()
+	res, err := h.gather(ctx, cep)
+	elapsed := time.Since(start)
+
+	switch {
+	case err == nil:
+		span.SetAttributes(attribute.String("cep.provider", res.Api))
+		slog.InfoContext(ctx, "consulta de CEP", "merge", true, "outcome", gateway.OutcomeOK, "provider", res.Api, "duration", elapsed)
+	case errors.Is(err, gateway.ErrCEPNotFound):
+		slog.InfoContext(ctx, "consulta de CEP", "merge", true, "outcome", "not_found", "duration", elapsed)
+	case errors.Is(err, errAllProvidersFailed):
+		span.SetStatus(codes.Error, err.Error())
+		slog.WarnContext(ctx, "consulta de CEP", "merge", true, "outcome", "all_failed", "duration", elapsed)
+	default:
+		span.SetStatus(codes.Error, err.Error())
+		slog.WarnContext(ctx, "consulta de CEP", "merge", true, "outcome", "timeout", "duration", elapsed)
+	}
+	return res, err
+}
+
+// gather calls every available provider at once and merges the answers that
+// arrive before config.Timeout, config.MergePreferredProvider first. When
+// none does, it fails like race.
+func (h *CepHandler) gather(ctx context.Context, cep string) (*dto.APIResponse, error) {
+	results, timedOut := h.queryAll(ctx, cep)
+
+	answers := make(map[string]*dto.CEP, len(results))
+	for _, res := range results {
+		if res.err == nil {
+			answers[res.provider] = res.resp
+		}
+	}
+	if len(answers) == 0 {
+		return nil, noAnswerError(results, timedOut)
+	}
+
+	preferred := h.config.MergePreferredProvider
+	ordered := make([]*dto.APIResponse, 0, len(answers))
+	if resp, ok := answers[preferred]; ok {
+		ordered = append(ordered, &dto.APIResponse{Data: resp, Api: preferred})
+	}
+	for _, res := range results {
+		if res.err == nil && res.provider != preferred {
+			ordered = append(ordered, &dto.APIResponse{Data: res.resp, Api: res.provider})
+		}
+	}
+	return dto.MergeCEPs(ordered), nil
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/cache"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockMergeProviders(brasilAPI, viaCEP *MockProvider) {
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{
+		Cep:     "01310100",
+		Estado:  "SP",
+		Cidade:  "São Paulo",
+		Bairro:  "Bela Vista",
+		Rua:     "Avenida Paulista",
+		Servico: "correios",
+	}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 20)
+	}).Return(&dto.CEP{
+		Cep:        "01310-100",
+		Logradouro: "Avenida Paulista",
+		Bairro:     "Bela Vista - SP",
+		Localidade: "São Paulo",
+		Uf:         "SP",
+		Ibge:       "3550308",
+		Ddd:        "11",
+	}, nil)
+}
+
+func TestCepHandlerGetCEPMergeWaitsForEveryProvider(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+	mockMergeProviders(brasilAPI, viaCEP)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100?merge=true", "01310100"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "BYPASS", recorder.Header().Get("X-Cache"))
+
+	var response dto.CEPResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "correios", response.Servico)
+	assert.Equal(t, "3550308", response.Ibge)
+	assert.Equal(t, "11", response.Ddd)
+	assert.Equal(t, "Bela Vista", response.Bairro)
+	assert.Equal(t, gateway.BrasilAPIName, response.Sources["bairro"])
+	assert.Equal(t, gateway.ViaCEPName, response.Sources["ibge"])
+}
+
+func TestCepHandlerGetCEPV2MergeThroughHeader(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+	mockMergeProviders(brasilAPI, viaCEP)
+
+	req := createRequest("GET", "/v2/01310100", "01310100")
+	req.Header.Set("X-CEP-Merge", "true")
+	recorder := httptest.NewRecorder()
+	handler.GetCEPV2(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response dto.Address
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "BrasilAPI,ViaCEP", response.Source)
+	assert.Equal(t, "11", response.Ddd)
+	assert.Equal(t, gateway.ViaCEPName, response.Sources["ddd"])
+	assert.Equal(t, gateway.BrasilAPIName, response.Sources["neighborhood"])
+}
+
+func TestCepHandlerGetCEPMergePrefersConfiguredProvider(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Second, MergePreferredProvider: gateway.ViaCEPName}
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewNoop(), config)
+	mockMergeProviders(brasilAPI, viaCEP)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100?merge=1", "01310100"))
+
+	var response dto.CEPResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "Bela Vista - SP", response.Bairro)
+	assert.Equal(t, gateway.ViaCEPName, response.Sources["bairro"])
+	assert.Equal(t, gateway.BrasilAPIName, response.Sources["servico"])
+}
+
+func TestCepHandlerGetCEPMergeKeepsAnswersBeforeTimeout(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	config := &configs.Config{Timeout: time.Millisecond * 50}
+	handler := NewCepHandler(gateway.NewRegistry(brasilAPI, viaCEP), cache.NewNoop(), config)
+
+	brasilAPI.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310100", Estado: "SP"}, nil)
+	viaCEP.On("Lookup", mock.Anything, "01310100").Run(func(args mock.Arguments) {
+		time.Sleep(time.Millisecond * 200)
+	}).Return(&dto.CEP{Cep: "01310-100", Ibge: "3550308"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/01310100?merge=true", "01310100"))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response dto.CEPResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "SP", response.Estado)
+	assert.Empty(t, response.Ibge)
+	assert.Equal(t, map[string]string{"cep": gateway.BrasilAPIName, "estado": gateway.BrasilAPIName}, response.Sources)
+}
+
+func TestCepHandlerGetCEPMergeFailures(t *testing.T) {
+	brasilAPI, viaCEP := setupProviders()
+	handler := setupHandler(brasilAPI, viaCEP)
+
+	brasilAPI.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+	viaCEP.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+	brasilAPI.On("Lookup", mock.Anything, "20040002").Return(nil, gateway.ErrCEPNotFound)
+	viaCEP.On("Lookup", mock.Anything, "20040002").Return(nil, errors.New("API error"))
+
+	recorder := httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/99999999?merge=true", "99999999"))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.GetCEP(recorder, createRequest("GET", "/20040002?merge=true", "20040002"))
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Len(t, decodeError(t, recorder).Providers, 2)
+}
+
+func TestMergeRequested(t *testing.T) {
+	assert.True(t, mergeRequested(httptest.NewRequest("GET", "/01310100?merge=true", nil)))
+	assert.False(t, mergeRequested(httptest.NewRequest("GET", "/01310100?merge=no", nil)))
+	assert.False(t, mergeRequested(httptest.NewRequest("GET", "/01310100", nil)))
+
+	req := httptest.NewRequest("GET", "/01310100", nil)
+	req.Header.Set("X-CEP-Merge", "1")
+	assert.True(t, mergeRequested(req))
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/ratelimit"
+)
+
+// StreamCEP reads one CEP per line from the request body and writes one
+// NDJSON result per line as soon as each lookup resolves, so results are not
+// in input order. The channels are unbuffered: a slow client stalls the
+// workers, which in turn stop reading the body.
+//
+// Each CEP costs a rate limit token, the first one paid by the request's own
+// token. A CEP read once the client is out of tokens is not looked up and
+// gets a rate_limited result instead.
+func (h *CepHandler) StreamCEP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	// Responses are written while the body is still being read; servers that
+	// cannot do full duplex simply buffer the body as usual. A stream can
+	// outlive the server's read and write timeouts, so both are lifted.
+	rc.EnableFullDuplex()
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	ceps := make(chan string)
+	results := make(chan *dto.BatchResult)
+
+	var wg sync.WaitGroup
+	for range max(h.config.StreamConcurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cep := range ceps {
+				select {
+				case results <- h.resolve(ctx, cep):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(results)
+		defer wg.Wait()
+		defer close(ceps)
+
+		scanner := bufio.NewScanner(r.Body)
+		for read := 0; scanner.Scan(); {
+			cep := strings.TrimSpace(scanner.Text())
+			if cep == "" {
+				continue
+			}
+			read++
+
+			if read > 1 && !ratelimit.Charge(ctx, 1).Allowed {
+				limited := &dto.BatchResult{Cep: cep, Error: &dto.BatchError{Code: batchErrorLimited, Message: errItemRateLimited.Error()}}
+				select {
+				case results <- limited:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			select {
+			case ceps <- cep:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			slog.WarnContext(ctx, "erro ao ler corpo da requisição", "error", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for result := range results {
+		if ctx.Err() != nil {
+			continue
+		}
+		if err := encoder.Encode(result); err != nil {
+			cancel()
+			continue
+		}
+		rc.Flush()
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/dto"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/cache"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/gateway"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func decodeNDJSON(t *testing.T, body string) map[string]*dto.BatchResult {
+	results := make(map[string]*dto.BatchResult)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var result dto.BatchResult
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results[result.Cep] = &result
+	}
+	return results
+}
+
+func TestCepHandlerStreamCEP(t *testing.T) {
+	provider := NewMockProvider(gateway.ViaCEPName)
+	config := &configs.Config{
+		Timeout:           time.Millisecond * 50,
+		StreamConcurrency: 2,
+	}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewNoop(), config)
+
+	provider.On("Lookup", mock.Anything, "01310100").Return(&dto.CEP{Cep: "01310-100", Logradouro: "Avenida Paulista", Uf: "SP"}, nil)
+	provider.On("Lookup", mock.Anything, "99999999").Return(nil, gateway.ErrCEPNotFound)
+
+	body := "01310100\n\nabc\n  99999999  \n"
+	req := httptest.NewRequest("POST", "/ceps/stream", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	handler.StreamCEP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, 3, strings.Count(recorder.Body.String(), "\n"))
+
+	results := decodeNDJSON(t, recorder.Body.String())
+	assert.Len(t, results, 3)
+	assert.Equal(t, "Avenida Paulista", results["01310100"].Data.Street)
+	assert.Equal(t, batchErrorInvalid, results["abc"].Error.Code)
+	assert.Equal(t, batchErrorNotFound, results["99999999"].Error.Code)
+}
+
+func TestCepHandlerStreamCEPBoundsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	config := &configs.Config{
+		Timeout:           time.Second,
+		StreamConcurrency: 4,
+	}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewNoop(), config)
+
+	provider.On("Lookup", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		current := inFlight.Add(1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 5)
+		inFlight.Add(-1)
+	}).Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	var body strings.Builder
+	for i := range 40 {
+		fmt.Fprintf(&body, "%08d\n", 1310100+i)
+	}
+
+	req := httptest.NewRequest("POST", "/ceps/stream", strings.NewReader(body.String()))
+	recorder := httptest.NewRecorder()
+
+	handler.StreamCEP(recorder, req)
+
+	assert.Len(t, decodeNDJSON(t, recorder.Body.String()), 40)
+	assert.LessOrEqual(t, peak.Load(), int32(4))
+	assert.True(t, recorder.Flushed)
+}
+
+func TestCepHandlerStreamCEPChargesRateLimitPerCEP(t *testing.T) {
+	provider := NewMockProvider(gateway.BrasilAPIName)
+	config := &configs.Config{
+		Timeout:           time.Second,
+		StreamConcurrency: 2,
+		RateLimitRPS:      0.001,
+		RateLimitBurst:    3,
+	}
+	handler := NewCepHandler(gateway.NewRegistry(provider), cache.NewNoop(), config)
+	limited := ratelimit.Middleware(config, RateLimited)(http.HandlerFunc(handler.StreamCEP))
+
+	provider.On("Lookup", mock.Anything, mock.Anything).Return(&dto.CEP{Cep: "01310-100"}, nil)
+
+	body := "01310100\n01310101\n01310102\n01310103\n01310104\n"
+	req := httptest.NewRequest("POST", "/ceps/stream", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	limited.ServeHTTP(recorder, req)
+
+	results := decodeNDJSON(t, recorder.Body.String())
+	assert.Len(t, results, 5)
+	for _, cep := range []string{"01310100", "01310101", "01310102"} {
+		assert.Nil(t, results[cep].Error)
+	}
+	for _, cep := range []string{"01310103", "01310104"} {
+		assert.Equal(t, batchErrorLimited, results[cep].Error.Code)
+	}
+	provider.AssertNumberOfCalls(t, "Lookup", 3)
+}
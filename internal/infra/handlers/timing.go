@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type timingEntry struct {
+	name     string
+	duration time.Duration
+	desc     string
+}
+
+// serverTiming collects the durations reported in a response's
+// Server-Timing header. A nil *serverTiming discards them.
+type serverTiming struct {
+	mu      sync.Mutex
+	entries []timingEntry
+}
+
+type serverTimingKey struct{}
+
+// withServerTiming makes lookups under ctx record their timings in st.
+func withServerTiming(ctx context.Context, st *serverTiming) context.Context {
+	return context.WithValue(ctx, serverTimingKey{}, st)
+}
+
+func serverTimingFrom(ctx context.Context) *serverTiming {
+	st, _ := ctx.Value(serverTimingKey{}).(*serverTiming)
+	return st
+}
+
+func (st *serverTiming) add(entries ...timingEntry) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.entries = append(st.entries, entries...)
+}
+
+func (st *serverTiming) snapshot() []timingEntry {
+	if st == nil {
+		return nil
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]timingEntry(nil), st.entries...)
+}
+
+// header formats the recorded entries plus total as a Server-Timing value,
+// e.g. `cache;dur=0.2;desc="miss", BrasilAPI;dur=85.1;desc="ok", total;dur=85.6`.
+func (st *serverTiming) header(total time.Duration) string {
+	entries := append(st.snapshot(), timingEntry{name: "total", duration: total})
+	metrics := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		metric := fmt.Sprintf("%s;dur=%.1f", entry.name, milliseconds(entry.duration))
+		if entry.desc != "" {
+			metric += fmt.Sprintf(";desc=%q", entry.desc)
+		}
+		metrics = append(metrics, metric)
+	}
+	return strings.Join(metrics, ", ")
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
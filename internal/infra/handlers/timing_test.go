@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerTimingHeader(t *testing.T) {
+	timing := &serverTiming{}
+	ctx := withServerTiming(context.Background(), timing)
+
+	serverTimingFrom(ctx).add(
+		timingEntry{name: "cache", duration: 200 * time.Microsecond, desc: "miss"},
+		timingEntry{name: "BrasilAPI", duration: 85 * time.Millisecond, desc: "ok"},
+	)
+
+	assert.Equal(t, `cache;dur=0.2;desc="miss", BrasilAPI;dur=85.0;desc="ok", total;dur=86.5`, timing.header(86500*time.Microsecond))
+}
+
+func TestServerTimingWithoutRecorder(t *testing.T) {
+	timing := serverTimingFrom(context.Background())
+
+	timing.add(timingEntry{name: "BrasilAPI", duration: time.Millisecond})
+
+	assert.Nil(t, timing)
+	assert.Equal(t, "total;dur=1.0", timing.header(time.Millisecond))
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		lines = append(lines, record)
+	}
+	return lines
+}
+
+func TestNewAddsContextAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, &configs.Config{LogLevel: "info", LogFormat: FormatJSON})
+
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-1")
+	ctx = With(ctx, slog.String("cep", "01001000"))
+	ctx = With(ctx, slog.String("provider", "ViaCEP"))
+	logger.InfoContext(ctx, "consulta de CEP")
+
+	lines := decodeLines(t, &buf)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "consulta de CEP", lines[0]["msg"])
+	assert.Equal(t, "req-1", lines[0]["request_id"])
+	assert.Equal(t, "01001000", lines[0]["cep"])
+	assert.Equal(t, "ViaCEP", lines[0]["provider"])
+}
+
+func TestNewRespectsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, &configs.Config{LogLevel: "warn", LogFormat: FormatJSON})
+
+	logger.Info("ignorado")
+	logger.Warn("registrado")
+
+	lines := decodeLines(t, &buf)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "registrado", lines[0]["msg"])
+}
+
+func TestNewTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, &configs.Config{LogLevel: "info", LogFormat: FormatText})
+
+	logger.Info("servidor escutando", "addr", ":8080")
+
+	assert.Contains(t, buf.String(), `msg="servidor escutando" addr=:8080`)
+}
+
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, ParseLevel("debug"))
+	assert.Equal(t, slog.LevelWarn, ParseLevel("WARN"))
+	assert.Equal(t, slog.LevelError, ParseLevel("error"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel("verbose"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel(""))
+}
+
+func TestMiddlewareLogsRequest(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(New(&buf, &configs.Config{LogLevel: "info", LogFormat: FormatJSON}))
+	defer slog.SetDefault(previous)
+
+	handler := middleware.RequestID(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/01001000", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-7")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "req-7", recorder.Header().Get(middleware.RequestIDHeader))
+	lines := decodeLines(t, &buf)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "request", lines[0]["msg"])
+	assert.Equal(t, "/01001000", lines[0]["path"])
+	assert.Equal(t, float64(http.StatusNotFound), lines[0]["status"])
+	assert.Equal(t, "req-7", lines[0]["request_id"])
+}
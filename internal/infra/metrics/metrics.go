@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every collector exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cep_requests_total",
+		Help: "CEP lookup requests answered, by HTTP status code.",
+	}, []string{"code"})
+
+	LookupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cep_lookup_duration_seconds",
+		Help:    "End-to-end duration of CEP lookups, by cache result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"cache"})
+
+	RaceWins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cep_race_wins_total",
+		Help: "Races won, by provider.",
+	}, []string{"provider"})
+
+	RaceFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cep_race_failures_total",
+		Help: "Races without a winner, by reason (not_found, all_failed, timeout).",
+	}, []string{"reason"})
+
+	CoalescedLookups = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cep_coalesced_lookups_total",
+		Help: "Cache misses that joined a race already in flight for the same CEP instead of starting one.",
+	})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cep_rate_limited_requests_total",
+		Help: "Requests rejected by the inbound rate limit, by client kind (api_key, ip).",
+	}, []string{"client"})
+
+	ProviderErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cep_provider_errors_total",
+		Help: "Provider call errors, by provider and type (timeout, status, decode, not_found, transport, circuit_open, rate_limited).",
+	}, []string{"provider", "type"})
+
+	ProviderDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cep_provider_request_duration_seconds",
+		Help:    "Duration of provider calls, by provider.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Requests,
+		LookupDuration,
+		RaceWins,
+		RaceFailures,
+		CoalescedLookups,
+		RateLimited,
+		ProviderErrors,
+		ProviderDuration,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts every request it serves in cep_requests_total, by the
+// status code answered. A handler that writes nothing answers 200, as
+// net/http does.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		Requests.WithLabelValues(strconv.Itoa(status)).Inc()
+	})
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerExposesCollectors(t *testing.T) {
+	RaceWins.WithLabelValues("BrasilAPI").Inc()
+	ProviderErrors.WithLabelValues("ViaCEP", "timeout").Inc()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `cep_race_wins_total{provider="BrasilAPI"}`)
+	assert.Contains(t, recorder.Body.String(), `cep_provider_errors_total{provider="ViaCEP",type="timeout"}`)
+	assert.Contains(t, recorder.Body.String(), "go_goroutines")
+}
+
+func TestMiddlewareCountsRequestsByStatus(t *testing.T) {
+	okBefore := testutil.ToFloat64(Requests.WithLabelValues("200"))
+	limitedBefore := testutil.ToFloat64(Requests.WithLabelValues("429"))
+
+	ok := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	limited := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	ok.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/ceps", nil))
+	limited.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/compare/01310100", nil))
+
+	assert.Equal(t, okBefore+1, testutil.ToFloat64(Requests.WithLabelValues("200")))
+	assert.Equal(t, limitedBefore+1, testutil.ToFloat64(Requests.WithLabelValues("429")))
+}
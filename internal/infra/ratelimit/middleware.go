@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+)
+
+// APIKeyHeader carries the partner API key.
+const APIKeyHeader = "X-API-Key"
+
+// Client kinds, as labelled in metrics.RateLimited.
+const (
+	ClientAPIKey = "api_key"
+	ClientIP     = "ip"
+)
+
+// Middleware limits requests per client. Requests carrying one of
+// config.RateLimitAPIKeys in X-API-Key share that key's bucket; any other
+// request, including one with an unknown key, is limited by client IP, so
+// made-up keys cannot get around the limit. A zero rate disables limiting
+// for that kind of client.
+//
+// Limited responses carry RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset; rejected ones also get Retry-After and are answered by
+// denied. The token taken here pays for one lookup; handlers that run more
+// charge the rest with Charge.
+func Middleware(config *configs.Config, denied http.HandlerFunc) func(http.Handler) http.Handler {
+	var byKey, byIP *Limiter
+	if config.RateLimitKeyRPS > 0 {
+		byKey = NewLimiter(config.RateLimitKeyRPS, config.RateLimitKeyBurst)
+	}
+	if config.RateLimitRPS > 0 {
+		byIP = NewLimiter(config.RateLimitRPS, config.RateLimitBurst)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter, kind, key := byIP, ClientIP, clientIP(r)
+			if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" && slices.Contains(config.RateLimitAPIKeys, apiKey) {
+				limiter, kind, key = byKey, ClientAPIKey, apiKey
+			}
+			if limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := &budget{limiter: limiter, key: key, kind: kind}
+			decision := client.charge(1)
+			SetHeaders(w, decision)
+			if !decision.Allowed {
+				denied(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), budgetKey{}, client)))
+		})
+	}
+}
+
+type budgetKey struct{}
+
+// budget is the bucket a request is charged to.
+type budget struct {
+	limiter *Limiter
+	key     string
+	kind    string
+}
+
+func (b *budget) charge(n int) Decision {
+	decision := b.limiter.AllowN(b.key, n)
+	if !decision.Allowed {
+		metrics.RateLimited.WithLabelValues(b.kind).Inc()
+	}
+	return decision
+}
+
+// Charge takes n more tokens from the bucket of the client behind ctx, for
+// handlers that run several lookups on one request. Requests that are not
+// rate limited, and n below one, are always allowed.
+func Charge(ctx context.Context, n int) Decision {
+	client, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok || n < 1 {
+		return Decision{Allowed: true}
+	}
+	return client.charge(n)
+}
+
+// Burst is the most tokens the client behind ctx can ever hold, and so the
+// most lookups one of its requests can pay for; zero when it is not rate
+// limited.
+func Burst(ctx context.Context) int {
+	client, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok {
+		return 0
+	}
+	return client.limiter.burst
+}
+
+// SetHeaders reports decision in the RateLimit-* headers, plus Retry-After
+// when it was rejected.
+func SetHeaders(w http.ResponseWriter, decision Decision) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(decision.Reset))
+	if !decision.Allowed {
+		w.Header().Set("Retry-After", seconds(decision.RetryAfter))
+	}
+}
+
+// clientIP is the host part of r.RemoteAddr, which middleware.RealIP
+// rewrites from the proxy headers when config.TrustProxyHeaders is set.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/AmandaIsrael/faster-cep-api/internal/infra/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupMiddleware(config *configs.Config) http.Handler {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	denied := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+	return Middleware(config, denied)(ok)
+}
+
+func serve(handler http.Handler, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/01310100", nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set(APIKeyHeader, apiKey)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMiddlewareLimitsByClientIP(t *testing.T) {
+	handler := setupMiddleware(&configs.Config{RateLimitRPS: 1, RateLimitBurst: 2})
+	before := testutil.ToFloat64(metrics.RateLimited.WithLabelValues(ClientIP))
+
+	first := serve(handler, "10.0.0.1:5000", "")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, first.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.1:5001", "").Code)
+
+	rejected := serve(handler, "10.0.0.1:5002", "")
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, "0", rejected.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rejected.Header().Get("Retry-After"))
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.RateLimited.WithLabelValues(ClientIP)))
+
+	assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.2:5000", "").Code)
+}
+
+func TestMiddlewareLimitsByKnownAPIKey(t *testing.T) {
+	handler := setupMiddleware(&configs.Config{
+		RateLimitRPS:      1,
+		RateLimitBurst:    1,
+		RateLimitKeyRPS:   1,
+		RateLimitKeyBurst: 3,
+		RateLimitAPIKeys:  []string{"partner"},
+	})
+
+	for range 3 {
+		recorder := serve(handler, "10.0.0.1:5000", "partner")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "3", recorder.Header().Get("RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve(handler, "10.0.0.2:5000", "partner").Code)
+
+	// The partner's bucket is its own: the IP still has its token.
+	assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.1:5000", "").Code)
+}
+
+func TestMiddlewareUnknownAPIKeyFallsBackToIP(t *testing.T) {
+	handler := setupMiddleware(&configs.Config{RateLimitRPS: 1, RateLimitBurst: 1, RateLimitAPIKeys: []string{"partner"}})
+
+	assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.1:5000", "made-up-1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(handler, "10.0.0.1:5000", "made-up-2").Code)
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	handler := setupMiddleware(&configs.Config{RateLimitAPIKeys: []string{"partner"}})
+
+	for range 5 {
+		recorder := serve(handler, "10.0.0.1:5000", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.1:5000", "partner").Code)
+}
+
+func TestMiddlewareChargesExtraTokens(t *testing.T) {
+	var charged []bool
+	handler := Middleware(&configs.Config{RateLimitRPS: 1, RateLimitBurst: 4}, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		charged = append(charged, Charge(r.Context(), 2).Allowed)
+	}))
+
+	serve(handler, "10.0.0.1:5000", "")
+	serve(handler, "10.0.0.1:5000", "")
+
+	assert.Equal(t, []bool{true, false}, charged)
+	assert.True(t, Charge(context.Background(), 100).Allowed)
+}
+
+func TestMiddlewareChargeNeverRefunds(t *testing.T) {
+	var bursts []int
+	handler := Middleware(&configs.Config{RateLimitRPS: 0.001, RateLimitBurst: 2}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bursts = append(bursts, Burst(r.Context()))
+		Charge(r.Context(), -1)
+	}))
+
+	assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.1:5000", "").Code)
+	assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.1:5000", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(handler, "10.0.0.1:5000", "").Code)
+	assert.Equal(t, []int{2, 2}, bursts)
+	assert.Zero(t, Burst(context.Background()))
+}
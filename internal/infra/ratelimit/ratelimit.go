@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped. A bucket that has
+// refilled completely carries no state, so dropping it changes nothing.
+const sweepInterval = time.Minute
+
+// Decision is the outcome of Allow, with the figures reported in the
+// RateLimit-* and Retry-After headers.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed. It
+	// is zero when Allowed.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a set of token buckets, one per key, each holding up to burst
+// tokens and refilling at rate tokens per second. Callers take tokens with
+// Allow or AllowN.
+type Limiter struct {
+	rate  float64
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   max(burst, 1),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket if there is one.
+func (l *Limiter) Allow(key string) Decision {
+	return l.AllowN(key, 1)
+}
+
+// AllowN takes n tokens from key's bucket if it holds that many, and none
+// otherwise. n larger than the burst is never allowed.
+func (l *Limiter) AllowN(key string, n int) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	decision := Decision{Limit: l.burst}
+	if b.tokens >= float64(n) {
+		b.tokens -= float64(n)
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = l.wait(float64(n) - b.tokens)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = l.wait(float64(l.burst) - b.tokens)
+	return decision
+}
+
+// Delay is how long until Allow would let a request for key through, zero
+// if it would now. It takes no token.
+func (l *Limiter) Delay(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		return 0
+	}
+	if tokens := l.refill(b, l.now()); tokens < 1 {
+		return l.wait(1 - tokens)
+	}
+	return 0
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+}
+
+// wait is how long the bucket takes to gain tokens.
+func (l *Limiter) wait(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
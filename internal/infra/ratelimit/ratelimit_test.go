@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(rate float64, burst int) (*Limiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(rate, burst)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestLimiterAllowsBurstThenRejects(t *testing.T) {
+	limiter, _ := newTestLimiter(1, 3)
+
+	for i := 2; i >= 0; i-- {
+		decision := limiter.Allow("client")
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 3, decision.Limit)
+		assert.Equal(t, i, decision.Remaining)
+	}
+
+	decision := limiter.Allow("client")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 0, decision.Remaining)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+	assert.Equal(t, 3*time.Second, decision.Reset)
+}
+
+func TestLimiterRefillsOverTime(t *testing.T) {
+	limiter, now := newTestLimiter(2, 2)
+
+	limiter.Allow("client")
+	limiter.Allow("client")
+	assert.False(t, limiter.Allow("client").Allowed)
+
+	*now = now.Add(250 * time.Millisecond)
+	decision := limiter.Allow("client")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 250*time.Millisecond, decision.RetryAfter)
+
+	*now = now.Add(250 * time.Millisecond)
+	assert.True(t, limiter.Allow("client").Allowed)
+
+	*now = now.Add(time.Hour)
+	decision = limiter.Allow("client")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 1, decision.Remaining)
+}
+
+func TestLimiterDelayDoesNotTakeTokens(t *testing.T) {
+	limiter, now := newTestLimiter(1, 1)
+
+	assert.Zero(t, limiter.Delay("client"))
+	assert.Zero(t, limiter.Delay("client"))
+	assert.True(t, limiter.Allow("client").Allowed)
+	assert.Equal(t, time.Second, limiter.Delay("client"))
+
+	*now = now.Add(time.Second)
+	assert.Zero(t, limiter.Delay("client"))
+}
+
+func TestLimiterKeepsClientsApart(t *testing.T) {
+	limiter, _ := newTestLimiter(1, 1)
+
+	assert.True(t, limiter.Allow("a").Allowed)
+	assert.False(t, limiter.Allow("a").Allowed)
+	assert.True(t, limiter.Allow("b").Allowed)
+}
+
+func TestLimiterDropsIdleBuckets(t *testing.T) {
+	limiter, now := newTestLimiter(1, 5)
+
+	limiter.Allow("idle")
+	limiter.Allow("busy")
+	*now = now.Add(sweepInterval)
+	for range 5 {
+		limiter.Allow("busy")
+	}
+	*now = now.Add(sweepInterval)
+	limiter.Allow("other")
+
+	assert.NotContains(t, limiter.buckets, "idle")
+	assert.Contains(t, limiter.buckets, "other")
+}
+
+func TestLimiterAllowNTakesAllOrNothing(t *testing.T) {
+	limiter, _ := newTestLimiter(1, 5)
+
+	assert.True(t, limiter.AllowN("10.0.0.1", 3).Allowed)
+
+	rejected := limiter.AllowN("10.0.0.1", 3)
+	assert.False(t, rejected.Allowed)
+	assert.Equal(t, 2, rejected.Remaining)
+	assert.Equal(t, time.Second, rejected.RetryAfter)
+
+	assert.True(t, limiter.AllowN("10.0.0.1", 2).Allowed)
+	assert.False(t, limiter.AllowN("10.0.0.2", 6).Allowed)
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmandaIsrael/faster-cep-api/configs"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddlewareNamesSpanAfterRoute(t *testing.T) {
+	exporter, restore := InstallInMemory()
+	defer restore()
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/{cep}", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, trace.SpanFromContext(r.Context()).SpanContext().IsValid())
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /{cep}", spans[0].Name)
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+	assert.Contains(t, spans[0].Attributes, attribute.Int("http.response.status_code", http.StatusGatewayTimeout))
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestMiddlewareContinuesIncomingTrace(t *testing.T) {
+	exporter, restore := InstallInMemory()
+	defer restore()
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/01001000", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+}
+
+func TestSetupWithoutExporter(t *testing.T) {
+	shutdown, err := Setup(context.Background(), &configs.Config{TracingExporter: ExporterNone})
+
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetupWithStdoutExporter(t *testing.T) {
+	_, restore := InstallInMemory()
+	defer restore()
+
+	shutdown, err := Setup(context.Background(), &configs.Config{TracingExporter: ExporterStdout, TracingSampleRatio: 1})
+
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
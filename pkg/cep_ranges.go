@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrCEPOutOfRange = errors.New("CEP não pertence a nenhuma faixa de CEP dos Correios")
+
+type cepRange struct {
+	uf    string
+	start int
+	end   int
+}
+
+// cepRanges is the Correios table of CEP ranges assigned to each UF, in CEP
+// order. Some UFs own more than one range.
+var cepRanges = []cepRange{
+	{uf: "SP", start: 1000000, end: 19999999},
+	{uf: "RJ", start: 20000000, end: 28999999},
+	{uf: "ES", start: 29000000, end: 29999999},
+	{uf: "MG", start: 30000000, end: 39999999},
+	{uf: "BA", start: 40000000, end: 48999999},
+	{uf: "SE", start: 49000000, end: 49999999},
+	{uf: "PE", start: 50000000, end: 56999999},
+	{uf: "AL", start: 57000000, end: 57999999},
+	{uf: "PB", start: 58000000, end: 58999999},
+	{uf: "RN", start: 59000000, end: 59999999},
+	{uf: "CE", start: 60000000, end: 63999999},
+	{uf: "PI", start: 64000000, end: 64999999},
+	{uf: "MA", start: 65000000, end: 65999999},
+	{uf: "PA", start: 66000000, end: 68899999},
+	{uf: "AP", start: 68900000, end: 68999999},
+	{uf: "AM", start: 69000000, end: 69299999},
+	{uf: "RR", start: 69300000, end: 69399999},
+	{uf: "AM", start: 69400000, end: 69899999},
+	{uf: "AC", start: 69900000, end: 69999999},
+	{uf: "DF", start: 70000000, end: 72799999},
+	{uf: "GO", start: 72800000, end: 72999999},
+	{uf: "DF", start: 73000000, end: 73699999},
+	{uf: "GO", start: 73700000, end: 76799999},
+	{uf: "RO", start: 76800000, end: 76999999},
+	{uf: "TO", start: 77000000, end: 77999999},
+	{uf: "MT", start: 78000000, end: 78899999},
+	{uf: "MS", start: 79000000, end: 79999999},
+	{uf: "PR", start: 80000000, end: 87999999},
+	{uf: "SC", start: 88000000, end: 89999999},
+	{uf: "RS", start: 90000000, end: 99999999},
+}
+
+// UFForCEP returns the UF whose Correios range contains the normalized
+// 8-digit CEP.
+func UFForCEP(cep string) (string, bool) {
+	number, err := strconv.Atoi(cep)
+	if err != nil || len(cep) != 8 {
+		return "", false
+	}
+	for _, r := range cepRanges {
+		if number >= r.start && number <= r.end {
+			return r.uf, true
+		}
+	}
+	return "", false
+}
+
+// ValidateCEPRange rejects normalized CEPs outside every Correios range,
+// such as 00000000.
+func ValidateCEPRange(cep string) error {
+	if _, ok := UFForCEP(cep); !ok {
+		return fmt.Errorf("%w: %s", ErrCEPOutOfRange, cep)
+	}
+	return nil
+}
+
+// MatchesUF reports whether uf agrees with the range of the normalized CEP.
+// An unknown CEP or an empty uf is not considered a contradiction.
+func MatchesUF(cep, uf string) bool {
+	expected, ok := UFForCEP(cep)
+	if !ok || uf == "" {
+		return true
+	}
+	return strings.EqualFold(expected, strings.TrimSpace(uf))
+}
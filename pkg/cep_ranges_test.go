@@ -0,0 +1,68 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUFForCEP(t *testing.T) {
+	expected := map[string]string{
+		"01001000": "SP",
+		"19999999": "SP",
+		"20040002": "RJ",
+		"69301000": "RR",
+		"69400000": "AM",
+		"69900000": "AC",
+		"70040010": "DF",
+		"73700000": "GO",
+		"76801000": "RO",
+		"99999999": "RS",
+	}
+
+	for cep, uf := range expected {
+		got, ok := UFForCEP(cep)
+		if !ok || got != uf {
+			t.Errorf("Expected CEP %s to belong to %s, got %q", cep, uf, got)
+		}
+	}
+}
+
+func TestCEPRangesReferenceKnownStates(t *testing.T) {
+	for _, r := range cepRanges {
+		if _, ok := LookupState(r.uf); !ok {
+			t.Errorf("Range %d-%d references unknown UF %s", r.start, r.end, r.uf)
+		}
+		if r.start > r.end {
+			t.Errorf("Range %d-%d of %s is inverted", r.start, r.end, r.uf)
+		}
+	}
+	for i := 1; i < len(cepRanges); i++ {
+		if cepRanges[i].start <= cepRanges[i-1].end {
+			t.Errorf("Range of %s overlaps the previous one", cepRanges[i].uf)
+		}
+	}
+}
+
+func TestValidateCEPRange(t *testing.T) {
+	for _, cep := range []string{"00000000", "00999999", "78900000"} {
+		if err := ValidateCEPRange(cep); !errors.Is(err, ErrCEPOutOfRange) {
+			t.Errorf("Expected CEP %s to be out of range, got %v", cep, err)
+		}
+	}
+
+	if err := ValidateCEPRange("01153000"); err != nil {
+		t.Errorf("Expected CEP 01153000 to be in range, got %v", err)
+	}
+}
+
+func TestMatchesUF(t *testing.T) {
+	if !MatchesUF("01153000", "SP") || !MatchesUF("01153000", "sp") {
+		t.Error("Expected SP to match CEP 01153000")
+	}
+	if MatchesUF("01153000", "RJ") {
+		t.Error("Expected RJ not to match CEP 01153000")
+	}
+	if !MatchesUF("01153000", "") {
+		t.Error("Expected an empty UF not to be flagged")
+	}
+}
@@ -0,0 +1,45 @@
+package pkg
+
+import "strings"
+
+type State struct {
+	Code   string
+	Name   string
+	Region string
+}
+
+var states = map[string]State{
+	"AC": {Code: "AC", Name: "Acre", Region: "Norte"},
+	"AL": {Code: "AL", Name: "Alagoas", Region: "Nordeste"},
+	"AP": {Code: "AP", Name: "Amapá", Region: "Norte"},
+	"AM": {Code: "AM", Name: "Amazonas", Region: "Norte"},
+	"BA": {Code: "BA", Name: "Bahia", Region: "Nordeste"},
+	"CE": {Code: "CE", Name: "Ceará", Region: "Nordeste"},
+	"DF": {Code: "DF", Name: "Distrito Federal", Region: "Centro-Oeste"},
+	"ES": {Code: "ES", Name: "Espírito Santo", Region: "Sudeste"},
+	"GO": {Code: "GO", Name: "Goiás", Region: "Centro-Oeste"},
+	"MA": {Code: "MA", Name: "Maranhão", Region: "Nordeste"},
+	"MT": {Code: "MT", Name: "Mato Grosso", Region: "Centro-Oeste"},
+	"MS": {Code: "MS", Name: "Mato Grosso do Sul", Region: "Centro-Oeste"},
+	"MG": {Code: "MG", Name: "Minas Gerais", Region: "Sudeste"},
+	"PA": {Code: "PA", Name: "Pará", Region: "Norte"},
+	"PB": {Code: "PB", Name: "Paraíba", Region: "Nordeste"},
+	"PR": {Code: "PR", Name: "Paraná", Region: "Sul"},
+	"PE": {Code: "PE", Name: "Pernambuco", Region: "Nordeste"},
+	"PI": {Code: "PI", Name: "Piauí", Region: "Nordeste"},
+	"RJ": {Code: "RJ", Name: "Rio de Janeiro", Region: "Sudeste"},
+	"RN": {Code: "RN", Name: "Rio Grande do Norte", Region: "Nordeste"},
+	"RS": {Code: "RS", Name: "Rio Grande do Sul", Region: "Sul"},
+	"RO": {Code: "RO", Name: "Rondônia", Region: "Norte"},
+	"RR": {Code: "RR", Name: "Roraima", Region: "Norte"},
+	"SC": {Code: "SC", Name: "Santa Catarina", Region: "Sul"},
+	"SP": {Code: "SP", Name: "São Paulo", Region: "Sudeste"},
+	"SE": {Code: "SE", Name: "Sergipe", Region: "Nordeste"},
+	"TO": {Code: "TO", Name: "Tocantins", Region: "Norte"},
+}
+
+// LookupState returns the name and region of a two-letter UF code.
+func LookupState(code string) (State, bool) {
+	state, ok := states[strings.ToUpper(strings.TrimSpace(code))]
+	return state, ok
+}
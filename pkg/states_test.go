@@ -0,0 +1,25 @@
+package pkg
+
+import "testing"
+
+func TestLookupState(t *testing.T) {
+	state, ok := LookupState("sp")
+	if !ok {
+		t.Fatal("Expected SP to be a known state")
+	}
+	if state.Code != "SP" || state.Name != "São Paulo" || state.Region != "Sudeste" {
+		t.Errorf("Unexpected state for SP: %+v", state)
+	}
+}
+
+func TestLookupStateCoversAllUFs(t *testing.T) {
+	if len(states) != 27 {
+		t.Errorf("Expected 27 UFs, got %d", len(states))
+	}
+}
+
+func TestLookupStateUnknown(t *testing.T) {
+	if _, ok := LookupState("XX"); ok {
+		t.Error("Expected XX to be unknown")
+	}
+}
@@ -0,0 +1,22 @@
+package pkg
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// FoldText reduces s to a form in which spellings that differ only in
+// accents, case or spacing compare equal: "Avenida  São João" and
+// "avenida sao joao" both fold to "avenida sao joao".
+func FoldText(s string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(stripAccents, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.Join(strings.Fields(strings.ToLower(folded)), " ")
+}
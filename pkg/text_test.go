@@ -0,0 +1,27 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"São Paulo", "sao paulo"},
+		{"SAO PAULO", "sao paulo"},
+		{"  Avenida   São João ", "avenida sao joao"},
+		{"Praça da Sé", "praca da se"},
+		{"Jardim Ângela", "jardim angela"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FoldText(tt.input))
+		})
+	}
+}
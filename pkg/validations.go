@@ -1,8 +1,44 @@
 package pkg
 
-import "regexp"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
+var (
+	ErrEmptyCEP          = errors.New("CEP é obrigatório")
+	ErrInvalidCEPCharset = errors.New("CEP deve conter apenas dígitos, hífen, ponto ou espaço")
+	ErrInvalidCEPLength  = errors.New("CEP deve conter exatamente 8 dígitos numéricos")
+)
+
+// IsValidCEP reports whether cep is accepted by NormalizeCEP, formatted or
+// not.
 func IsValidCEP(cep string) bool {
-	matched, _ := regexp.MatchString(`^\d{8}$`, cep)
-	return matched
+	_, err := NormalizeCEP(cep)
+	return err == nil
+}
+
+// NormalizeCEP strips the separators commonly used when writing a CEP
+// ("01153-000", "01.153-000", "01153 000") and returns the canonical
+// 8-digit string.
+func NormalizeCEP(cep string) (string, error) {
+	var digits strings.Builder
+	for _, r := range cep {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '-' || r == '.' || r == ' ' || r == '\t':
+		default:
+			return "", fmt.Errorf("%w: %q", ErrInvalidCEPCharset, r)
+		}
+	}
+
+	switch {
+	case digits.Len() == 0:
+		return "", ErrEmptyCEP
+	case digits.Len() != 8:
+		return "", fmt.Errorf("%w: %d encontrados", ErrInvalidCEPLength, digits.Len())
+	}
+	return digits.String(), nil
 }
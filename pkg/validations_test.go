@@ -1,12 +1,19 @@
 package pkg
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestValidCEP(t *testing.T) {
 	validCEPs := []string{
 		"12345678",
 		"00000000",
 		"98765432",
+		"01153-000",
+		"01.153-000",
+		"01153 000",
+		"12-345678",
 	}
 
 	for _, cep := range validCEPs {
@@ -22,7 +29,9 @@ func TestInvalidCEP(t *testing.T) {
 		"abcdefgh",
 		"1234567a",
 		"123456789",
-		"12-345678",
+		"01153_000",
+		"01153-00",
+		"--------",
 		"",
 	}
 
@@ -31,4 +40,43 @@ func TestInvalidCEP(t *testing.T) {
 			t.Errorf("Expected CEP %s to be invalid", cep)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestNormalizeCEP(t *testing.T) {
+	formattedCEPs := map[string]string{
+		"01153000":    "01153000",
+		"01153-000":   "01153000",
+		"01.153-000":  "01153000",
+		"01153 000":   "01153000",
+		" 01153-000 ": "01153000",
+	}
+
+	for input, expected := range formattedCEPs {
+		cep, err := NormalizeCEP(input)
+		if err != nil {
+			t.Errorf("Expected CEP %q to be valid, got %v", input, err)
+		}
+		if cep != expected {
+			t.Errorf("Expected CEP %q to normalize to %s, got %s", input, expected, cep)
+		}
+	}
+}
+
+func TestNormalizeCEPErrors(t *testing.T) {
+	invalidCEPs := map[string]error{
+		"":          ErrEmptyCEP,
+		" - ":       ErrEmptyCEP,
+		"1234":      ErrInvalidCEPLength,
+		"123456789": ErrInvalidCEPLength,
+		"01153-00":  ErrInvalidCEPLength,
+		"abcdefgh":  ErrInvalidCEPCharset,
+		"01153/000": ErrInvalidCEPCharset,
+		"1234567a":  ErrInvalidCEPCharset,
+		"０1153000":  ErrInvalidCEPCharset,
+	}
+
+	for input, expected := range invalidCEPs {
+		if _, err := NormalizeCEP(input); !errors.Is(err, expected) {
+			t.Errorf("Expected CEP %q to fail with %v, got %v", input, expected, err)
+		}
+	}
+}